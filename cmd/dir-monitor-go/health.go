@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/monitor"
+)
+
+// 健康检查命令默认请求超时
+const DefaultHealthRequestTimeout = 5 * time.Second
+
+// runHealthCommand 实现 `dir-monitor-go health`：查询运行中实例的 /healthz 或 /readyz。
+// 返回进程退出码：0 健康，1 不健康，2 无法检查。
+func runHealthCommand(args []string) int {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	configPath := fs.String("config", "configs/config.json", "配置文件路径（用于读取 settings.metrics_listen）")
+	addr := fs.String("addr", "", "运行实例的 HTTP 地址，覆盖配置中的 metrics_listen")
+	ready := fs.Bool("ready", false, "检查就绪状态（/readyz）而非存活状态（/healthz）")
+	asJSON := fs.Bool("json", false, "输出原始 JSON")
+	timeout := fs.Duration("timeout", DefaultHealthRequestTimeout, "请求超时时间")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	target := strings.TrimSpace(*addr)
+	if target == "" {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+			return 2
+		}
+		target = strings.TrimSpace(cfg.Settings.MetricsListen)
+		if target == "" {
+			fmt.Fprintln(os.Stderr, "配置未启用 settings.metrics_listen，请使用 -addr 指定地址")
+			return 2
+		}
+	}
+	if strings.HasPrefix(target, ":") {
+		target = "127.0.0.1" + target
+	}
+
+	path := "/healthz"
+	if *ready {
+		path = "/readyz"
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	resp, err := client.Get("http://" + target + path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "无法连接运行实例: %v\n", err)
+		return 2
+	}
+	defer resp.Body.Close()
+
+	var report monitor.HealthReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		fmt.Fprintf(os.Stderr, "解析健康检查结果失败: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		fmt.Printf("状态: %s (存活: %v, 就绪: %v, 检查时间: %s)\n",
+			report.Status, report.Live, report.Ready, report.CheckedAt.Format(time.RFC3339))
+		for _, c := range report.Checks {
+			line := fmt.Sprintf("  [%s] %s", c.Status, c.Name)
+			if c.Message != "" {
+				line += ": " + c.Message
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
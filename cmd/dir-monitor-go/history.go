@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/history"
+	"dir-monitor-go/internal/monitor"
+)
+
+// 历史查询默认返回条数
+const DefaultHistoryLimit = 50
+
+// runHistoryCommand 实现 `dir-monitor-go history`：查询执行记录。
+// 返回进程退出码：0 成功，2 参数或读取错误。
+func runHistoryCommand(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	configPath := fs.String("config", "configs/config.json", "配置文件路径（用于读取 settings.data_dir）")
+	dataDir := fs.String("data-dir", "", "数据目录，覆盖配置中的 data_dir")
+	monitorID := fs.String("monitor", "", "按监控项 ID 过滤")
+	pathGlob := fs.String("path", "", "按触发路径 glob 过滤（匹配完整路径或文件名）")
+	status := fs.String("status", "", "按状态过滤: success, failure, timeout")
+	since := fs.String("since", "", "起始时间：RFC3339、2006-01-02 或相对时长（如 24h）")
+	until := fs.String("until", "", "结束时间：RFC3339、2006-01-02 或相对时长（如 1h）")
+	limit := fs.Int("limit", DefaultHistoryLimit, "最多显示的记录数，0 不限制")
+	asJSON := fs.Bool("json", false, "以 JSON Lines 输出完整记录（含输出片段）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	filter := history.Filter{
+		MonitorID: *monitorID,
+		PathGlob:  *pathGlob,
+		Status:    *status,
+		Limit:     *limit,
+	}
+	switch filter.Status {
+	case "", history.StatusSuccess, history.StatusFailure, history.StatusTimeout:
+	default:
+		fmt.Fprintf(os.Stderr, "未知状态: %s\n", filter.Status)
+		return 2
+	}
+
+	now := time.Now()
+	var err error
+	if filter.Since, err = parseHistoryTime(*since, now, false); err != nil {
+		fmt.Fprintf(os.Stderr, "无效的 -since: %v\n", err)
+		return 2
+	}
+	if filter.Until, err = parseHistoryTime(*until, now, true); err != nil {
+		fmt.Fprintf(os.Stderr, "无效的 -until: %v\n", err)
+		return 2
+	}
+
+	var dir string
+	if d := strings.TrimSpace(*dataDir); d != "" {
+		dir = filepath.Join(d, monitor.HistorySubdir)
+	} else {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+			return 2
+		}
+		dir = monitor.HistoryDir(cfg)
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "执行记录目录不可用: %v\n", err)
+		return 2
+	}
+	store, err := history.Open(dir, 0, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开执行记录失败: %v\n", err)
+		return 2
+	}
+	defer store.Close()
+
+	records, err := store.Query(filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "查询执行记录失败: %v\n", err)
+		return 2
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, rec := range records {
+			_ = enc.Encode(rec)
+		}
+		return 0
+	}
+
+	if len(records) == 0 {
+		fmt.Println("没有匹配的执行记录")
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "START\tMONITOR\tSTATUS\tEXIT\tATTEMPT\tDURATION\tPATHS")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%v\t%s\n",
+			rec.Start.Local().Format(time.DateTime),
+			rec.MonitorID,
+			rec.Status,
+			rec.ExitCode,
+			rec.Attempt,
+			rec.Duration().Round(time.Millisecond),
+			strings.Join(rec.Paths, ","))
+	}
+	tw.Flush()
+	return 0
+}
+
+// parseHistoryTime 解析 RFC3339 时间、本地日期或相对当前时间的时长。
+// endOfDay 为 true 时日期解析为当天结束，使 -until 包含当天。
+func parseHistoryTime(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return t, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q", value)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/metrics"
+	"dir-monitor-go/internal/monitor"
+)
+
+const (
+	// HTTP 服务关闭等待时间
+	DefaultHTTPShutdownTimeout = 5 * time.Second
+	// 读取请求头超时
+	DefaultHTTPReadHeaderTimeout = 5 * time.Second
+)
+
+// startHTTPServer 在 settings.metrics_listen 上启动 HTTP 服务（/metrics、/healthz、/readyz、/pending），返回关闭函数
+func startHTTPServer(addr string, log *logger.Logger, health func() monitor.HealthReport, pending func() []monitor.PendingItem) (func(), error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		report := health()
+		writeHealth(w, report, report.Live)
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		report := health()
+		writeHealth(w, report, report.Ready)
+	})
+	mux.HandleFunc("/pending", func(w http.ResponseWriter, _ *http.Request) {
+		items := pending()
+		if items == nil {
+			items = []monitor.PendingItem{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(items)
+	})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: DefaultHTTPReadHeaderTimeout,
+	}
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("[HTTP] 服务异常退出: %v", err)
+		}
+	}()
+	log.Info("[HTTP] 指标与健康检查服务已启动: http://%s (/metrics, /healthz, /readyz, /pending)", ln.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultHTTPShutdownTimeout)
+		defer cancel()
+		_ = srv.Shutdown(ctx)
+	}, nil
+}
+
+// writeHealth 以 JSON 输出健康检查结果，ok 为 false 时返回 503
+func writeHealth(w http.ResponseWriter, report monitor.HealthReport, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+}
@@ -31,6 +31,18 @@ var (
 )
 
 func main() {
+	// 子命令
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "health":
+			os.Exit(runHealthCommand(os.Args[2:]))
+		case "history":
+			os.Exit(runHistoryCommand(os.Args[2:]))
+		case "pending":
+			os.Exit(runPendingCommand(os.Args[2:]))
+		}
+	}
+
 	// 解析命令行参数
 	configPath := flag.String("config", "configs/config.json", "配置文件路径")
 	stopFile := flag.String("stop-file", "", "当该文件出现时优雅退出（测试/集成用）")
@@ -121,6 +133,9 @@ func main() {
 
 		// 应用日志配置
 		log.SetCaller(logShowCaller)
+		if cfg.Settings.LogFormat == config.LogFormatJSON {
+			log.SetFormat(logger.FormatJSON)
+		}
 
 		// 提示最终日志配置
 		levelName := map[logger.LogLevel]string{logger.DEBUG: "debug", logger.INFO: "info", logger.WARN: "warn", logger.ERROR: "error"}[level]
@@ -169,6 +184,33 @@ func main() {
 	}
 	log.Info("[生产模式] 监控器启动成功")
 
+	// 可选的指标 HTTP 服务
+	if addr := strings.TrimSpace(cfg.Settings.MetricsListen); addr != "" {
+		stopHTTP, err := startHTTPServer(addr, log, monitorManager.HealthReport, monitorManager.PendingItems)
+		if err != nil {
+			log.Error("启动指标服务失败: %v", err)
+		} else {
+			defer stopHTTP()
+		}
+	}
+
+	// 配置热重载：SIGHUP 或配置文件变化时重新加载
+	reloader := newConfigReloader(*configPath, log, monitorManager)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reloader.Reload("SIGHUP")
+		}
+	}()
+	if stopConfigWatch, err := watchConfigFile(*configPath, log, func() {
+		reloader.Reload("配置文件变化")
+	}); err != nil {
+		log.Warn("监听配置文件失败，仅支持 SIGHUP 重载: %v", err)
+	} else {
+		defer stopConfigWatch()
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/monitor"
+)
+
+// runPendingCommand 实现 `dir-monitor-go pending`：列出因不在调度时间内而延后执行的事件。
+// 返回进程退出码：0 成功，2 参数或读取错误。
+func runPendingCommand(args []string) int {
+	fs := flag.NewFlagSet("pending", flag.ContinueOnError)
+	configPath := fs.String("config", "configs/config.json", "配置文件路径（用于读取 settings.data_dir）")
+	dataDir := fs.String("data-dir", "", "数据目录，覆盖配置中的 data_dir")
+	monitorID := fs.String("monitor", "", "按监控项 ID 过滤")
+	asJSON := fs.Bool("json", false, "以 JSON 输出")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var path string
+	if d := strings.TrimSpace(*dataDir); d != "" {
+		path = filepath.Join(d, monitor.PendingFileName)
+	} else {
+		cfg, err := config.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "加载配置文件失败: %v\n", err)
+			return 2
+		}
+		path = monitor.PendingFile(cfg)
+	}
+
+	items, err := monitor.LoadPending(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取延后事件失败: %v\n", err)
+		return 2
+	}
+	if *monitorID != "" {
+		filtered := items[:0]
+		for _, item := range items {
+			if item.MonitorID == *monitorID {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if *asJSON {
+		if items == nil {
+			items = []monitor.PendingItem{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(items)
+		return 0
+	}
+
+	if len(items) == 0 {
+		fmt.Println("没有延后执行的事件")
+		return 0
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DUE\tMONITOR\tEVENT\tADDED\tPATH")
+	for _, item := range items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			item.Due.Local().Format(time.DateTime),
+			item.MonitorID,
+			item.EventType,
+			item.Added.Local().Format(time.DateTime),
+			item.Path)
+	}
+	tw.Flush()
+	return 0
+}
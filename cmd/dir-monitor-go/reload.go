@@ -0,0 +1,110 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/monitor"
+)
+
+// 配置文件变更后的防抖时间（编辑器保存通常会产生多次写入/重命名事件）
+const DefaultConfigReloadDebounce = 500 * time.Millisecond
+
+// configReloader 负责重新加载配置文件并应用到监控管理器
+type configReloader struct {
+	path    string
+	log     *logger.Logger
+	manager *monitor.MonitorManager
+	mu      sync.Mutex
+}
+
+func newConfigReloader(path string, log *logger.Logger, manager *monitor.MonitorManager) *configReloader {
+	return &configReloader{path: path, log: log, manager: manager}
+}
+
+// Reload 重新执行 LoadConfig/Validate；新配置无效时保留旧配置继续运行
+func (r *configReloader) Reload(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.log.Info("[Reload] 收到配置重载请求 (%s): %s", reason, r.path)
+
+	cfg, err := config.LoadConfig(r.path)
+	if err != nil {
+		r.log.Error("[Reload] 新配置无效，继续使用旧配置: %v", err)
+		return
+	}
+
+	if err := r.manager.Reload(cfg); err != nil {
+		r.log.Error("[Reload] 应用新配置失败，继续使用旧配置: %v", err)
+		return
+	}
+
+	r.log.Info("[Reload] 配置重载成功，监控项 %d 个", len(cfg.Monitors))
+}
+
+// watchConfigFile 监听配置文件所在目录，配置文件变化时触发重载。
+// 监听目录而非文件本身，以兼容编辑器“写临时文件再重命名”的保存方式。
+func watchConfigFile(path string, log *logger.Logger, onChange func()) (func(), error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+	if err := w.Add(filepath.Dir(absPath)); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != absPath {
+					continue
+				}
+				if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(DefaultConfigReloadDebounce, onChange)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				log.Warn("[Reload] 配置文件监听错误: %v", err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			close(done)
+			_ = w.Close()
+		})
+	}
+	return stop, nil
+}
@@ -2,9 +2,9 @@ module dir-monitor-go
 
 go 1.25.3
 
-require github.com/fsnotify/fsnotify v1.9.0
-
 require (
-	github.com/adhocore/gronx v1.19.6 // indirect
-	golang.org/x/sys v0.37.0 // indirect
+	github.com/adhocore/gronx v1.19.6
+	github.com/fsnotify/fsnotify v1.9.0
 )
+
+require golang.org/x/sys v0.37.0 // indirect
@@ -0,0 +1,92 @@
+// Package calendar 调度使用的日期日历（如公共假日）。
+//
+// 日历文件每行一个日期（YYYY-MM-DD），支持空行与 # 注释（整行或行尾）。
+// exclude 模式下列出的日期不执行；include 模式下只有列出的日期执行。
+// 日期按监控项的时区判断。
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// 日历模式
+const (
+	ModeExclude = "exclude"
+	ModeInclude = "include"
+)
+
+// DateLayout 日历文件中的日期格式
+const DateLayout = "2006-01-02"
+
+// Calendar 已加载的日历
+type Calendar struct {
+	Name  string
+	Mode  string
+	dates map[string]bool
+}
+
+// Load 读取日历文件；mode 为空时使用 exclude
+func Load(name, path, mode string) (*Calendar, error) {
+	switch mode {
+	case "":
+		mode = ModeExclude
+	case ModeExclude, ModeInclude:
+	default:
+		return nil, fmt.Errorf("unknown calendar mode: %s", mode)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open calendar file: %v", err)
+	}
+	defer f.Close()
+
+	c := &Calendar{Name: name, Mode: mode, dates: make(map[string]bool)}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		d, err := time.Parse(DateLayout, line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid date %q", path, lineNo, line)
+		}
+		c.dates[d.Format(DateLayout)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar file: %v", err)
+	}
+	return c, nil
+}
+
+// Contains 判断 t 所在日期（按 t 的时区）是否列在日历中
+func (c *Calendar) Contains(t time.Time) bool {
+	return c.dates[t.Format(DateLayout)]
+}
+
+// Allows 判断 t 所在日期是否允许执行；nil 日历允许所有日期
+func (c *Calendar) Allows(t time.Time) bool {
+	if c == nil {
+		return true
+	}
+	if c.Mode == ModeInclude {
+		return c.Contains(t)
+	}
+	return !c.Contains(t)
+}
+
+// Len 返回日历中的日期数
+func (c *Calendar) Len() int {
+	return len(c.dates)
+}
@@ -0,0 +1,63 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCalendar(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCalendarModes(t *testing.T) {
+	path := writeCalendar(t, "# 公共假日\n2026-01-01\n\n2026-12-25  # Christmas\n")
+	holiday := time.Date(2026, 12, 25, 10, 0, 0, 0, time.UTC)
+	workday := time.Date(2026, 12, 24, 10, 0, 0, 0, time.UTC)
+
+	exclude, err := Load("holidays", path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exclude.Len() != 2 || exclude.Mode != ModeExclude {
+		t.Fatalf("loaded %d dates, mode %s", exclude.Len(), exclude.Mode)
+	}
+	if exclude.Allows(holiday) || !exclude.Allows(workday) {
+		t.Error("exclude calendar should reject listed dates only")
+	}
+
+	include, err := Load("holidays", path, ModeInclude)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !include.Allows(holiday) || include.Allows(workday) {
+		t.Error("include calendar should allow listed dates only")
+	}
+
+	// 日期按时间所在时区判断：UTC 12-25 01:00 在纽约仍是 12-24
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("zoneinfo not available")
+	}
+	if !exclude.Allows(time.Date(2026, 12, 25, 1, 0, 0, 0, time.UTC).In(ny)) {
+		t.Error("date should be evaluated in the time's location")
+	}
+}
+
+func TestCalendarInvalid(t *testing.T) {
+	if _, err := Load("bad", writeCalendar(t, "2026-13-01\n"), ""); err == nil {
+		t.Error("invalid date should be rejected")
+	}
+	if _, err := Load("bad", writeCalendar(t, ""), "sometimes"); err == nil {
+		t.Error("unknown mode should be rejected")
+	}
+	if _, err := Load("missing", filepath.Join(t.TempDir(), "none.txt"), ""); err == nil {
+		t.Error("missing file should be rejected")
+	}
+}
@@ -5,9 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/adhocore/gronx"
+
+	"dir-monitor-go/internal/calendar"
+	"dir-monitor-go/internal/ignore"
 	"dir-monitor-go/internal/model"
+	"dir-monitor-go/internal/pattern"
 )
 
 const (
@@ -18,10 +29,147 @@ const (
 	DefaultDirectoryStabilityQuietMs        = 2000
 	DefaultExecutionDedupIntervalSeconds    = 5
 	DefaultDirectoryStabilityTimeoutSeconds = 30
-	DefaultRetryAttempts                    = 3
 	DefaultRetryDelaySeconds                = 5
+	DefaultRetryBackoff                     = RetryBackoffFixed
+	DefaultRetryMaxDelaySeconds             = 300
 	DefaultHealthCheckIntervalSeconds       = 60
+	DefaultHealthErrorThreshold             = 10
 	DefaultLogMaxBackups                    = 5
+	DefaultDataDir                          = "data"
+	DefaultHistoryRetentionDays             = 30
+	DefaultHistoryOutputMaxBytes            = 4096
+	DefaultOutputDir                        = "logs"
+	DefaultOutputMaxBytes                   = 10 * 1024 * 1024
+	DefaultOutputTailBytes                  = 4096
+	DefaultOutputLogLevel                   = "debug"
+	DefaultQuarantineDir                    = "quarantine"
+	DefaultPollIntervalMs                   = 2000
+	DefaultJournalCompactThreshold          = 1000
+)
+
+// 日志输出格式
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// 重试退避策略
+const (
+	RetryBackoffFixed       = "fixed"
+	RetryBackoffLinear      = "linear"
+	RetryBackoffExponential = "exponential"
+	RetryBackoffJitter      = "jitter"
+)
+
+// 命令变量替换模式
+const (
+	// SubstitutionQuote 变量值按 shell 规则加引号后替换（默认）
+	SubstitutionQuote = "quote"
+	// SubstitutionRaw 变量值原样替换（旧行为，存在注入风险）
+	SubstitutionRaw = "raw"
+)
+
+// CommandVariables 命令与参数中可引用的变量，监控项 env 中的变量也可引用
+var CommandVariables = []string{
+	"EVENT_TYPE", "FILE_PATH", "OLD_PATH", "FILE_NAME", "FILE_DIR", "EVENT_TIME",
+	"FILE_LIST", "FILE_COUNT", "MANIFEST", "TRIGGER_FILE",
+}
+
+// rawVariableRef 匹配命令中的 ${raw:VAR} 引用
+var rawVariableRef = regexp.MustCompile(`\$\{raw:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// validateRawVariables ${raw:VAR} 只能引用 CommandVariables 或监控项 env 中的变量
+func validateRawVariables(monitor Monitor) error {
+	for _, s := range append([]string{monitor.Command}, monitor.Args...) {
+		for _, ref := range rawVariableRef.FindAllStringSubmatch(s, -1) {
+			name := ref[1]
+			if _, ok := monitor.Env[name]; ok {
+				continue
+			}
+			known := false
+			for _, v := range CommandVariables {
+				if v == name {
+					known = true
+					break
+				}
+			}
+			if !known {
+				return fmt.Errorf("unknown variable in %s", ref[0])
+			}
+		}
+	}
+	return nil
+}
+
+// 批处理模式
+const (
+	BatchModeFileList = "file_list"
+	BatchModeManifest = "manifest"
+	BatchModeStdin    = "stdin"
+	BatchModePerFile  = "per_file"
+)
+
+// 清单文件格式
+const (
+	ManifestFormatLines = "lines"
+	ManifestFormatJSON  = "json"
+)
+
+// OutputLevelNone 命令输出不写入日志
+const OutputLevelNone = "none"
+
+// 文件监控后端
+const (
+	WatcherInotify = "inotify"
+	WatcherPoll    = "poll"
+	// WatcherAuto 网络文件系统（NFS、SMB/CIFS、FUSE 等）使用轮询，其余使用 inotify
+	WatcherAuto = "auto"
+)
+
+// 不在调度时间内到达的文件的处理方式
+const (
+	// OutsideScheduleSkip 直接跳过（默认）
+	OutsideScheduleSkip = "skip"
+	// OutsideScheduleDefer 延后到调度窗口下次开启时执行
+	OutsideScheduleDefer = "defer"
+)
+
+// 防抖触发边沿
+const (
+	// DebounceTrailing 静默期结束后处理缓冲的全部事件（默认）
+	DebounceTrailing = "trailing"
+	// DebounceLeading 第一个事件立即处理，静默期结束前的后续事件被忽略
+	DebounceLeading = "leading"
+	// DebounceBoth 第一个事件立即处理，静默期内的后续事件在静默期结束后处理
+	DebounceBoth = "both"
+)
+
+// 文件写入完成的判断策略
+const (
+	// ReadinessQuiet 目录静默期结束即视为完成（默认）
+	ReadinessQuiet = "quiet"
+	// ReadinessCloseWrite 写入方关闭文件（inotify IN_CLOSE_WRITE）后视为完成
+	ReadinessCloseWrite = "close_write"
+	// ReadinessSizeStable 连续多次检查大小与修改时间不变后视为完成
+	ReadinessSizeStable = "size_stable"
+	// ReadinessMarker 同名标记文件（如 foo.csv.done）出现后视为完成
+	ReadinessMarker = "marker"
+	// ReadinessExclusiveOpen 没有进程以写方式打开文件时视为完成；Linux 以外的平台
+	// 只能通过 flock 发现同样加锁的写入方
+	ReadinessExclusiveOpen = "exclusive_open"
+
+	DefaultReadinessChecks         = 3
+	DefaultReadinessMarkerSuffix   = ".done"
+	DefaultReadinessTimeoutSeconds = 3600
+)
+
+// 执行后文件处置动作
+const (
+	DispositionMove       = "move"
+	DispositionCopy       = "copy"
+	DispositionDelete     = "delete"
+	DispositionRename     = "rename"
+	DispositionQuarantine = "quarantine"
 )
 
 type Config struct {
@@ -31,6 +179,9 @@ type Config struct {
 	Settings model.Settings    `json:"settings"`
 	LogFile  string            `json:"log_file,omitempty"`
 	LogLevel string            `json:"log_level,omitempty"`
+
+	// Calendars 按名称定义的日期日历，监控项通过 calendar 引用
+	Calendars map[string]CalendarConfig `json:"calendars,omitempty"`
 }
 
 type Monitor struct {
@@ -38,12 +189,330 @@ type Monitor struct {
 	Name            string   `json:"name,omitempty"`
 	Description     string   `json:"description,omitempty"`
 	Directory       string   `json:"directory"`
-	Command         string   `json:"command"`
+	Command         string   `json:"command,omitempty"`
+	Args            []string `json:"args,omitempty"`
 	FilePatterns    []string `json:"file_patterns"`
 	Timeout         int      `json:"timeout"`
 	Schedule        string   `json:"schedule,omitempty"`
 	Enabled         bool     `json:"enabled,omitempty"`
 	DebounceSeconds int      `json:"debounce_seconds,omitempty"`
+	// CaseInsensitive 为 true 时 file_patterns 匹配不区分大小写
+	CaseInsensitive bool `json:"case_insensitive,omitempty"`
+	// MaxWaitSeconds 事件持续到达时最长等待时间（秒），为 0 时使用 settings.directory_stability_timeout_seconds
+	MaxWaitSeconds int `json:"max_wait_seconds,omitempty"`
+	// DebounceEdge 触发边沿：trailing（默认）、leading 或 both
+	DebounceEdge string `json:"debounce_edge,omitempty"`
+	// Recursive 为 true 时同时处理子目录中的文件，新建的子目录自动加入监控；默认只处理监控目录顶层的文件
+	Recursive bool `json:"recursive,omitempty"`
+	// MaxDepth 递归时处理的最大子目录层数（1 表示只到直接子目录），0 表示不限制
+	MaxDepth int `json:"max_depth,omitempty"`
+	// Watcher 文件监控后端：inotify（默认）、poll 或 auto
+	Watcher string `json:"watcher,omitempty"`
+	// OutsideSchedule 不在调度时间内到达的文件：skip（默认）或 defer
+	OutsideSchedule string `json:"outside_schedule,omitempty"`
+	// Timezone 计算调度与日历的 IANA 时区（如 Asia/Shanghai），为空使用本地时区
+	Timezone string `json:"timezone,omitempty"`
+	// Calendar 引用 calendars 中的日历，日历不允许的日期视为不在调度时间内
+	Calendar string `json:"calendar,omitempty"`
+	// Ignore 监控项的忽略规则（gitignore 语法），追加在全局规则之后，可用 ! 重新包含
+	Ignore []string `json:"ignore,omitempty"`
+	// Events 触发命令的事件类型（created、modified、renamed、deleted），
+	// 为空时为 created、modified、renamed
+	Events []string `json:"events,omitempty"`
+
+	Retry  *RetryConfig  `json:"retry,omitempty"`
+	Batch  *BatchConfig  `json:"batch,omitempty"`
+	Output *OutputConfig `json:"output,omitempty"`
+
+	OnSuccess *DispositionConfig `json:"on_success,omitempty"`
+	OnFailure *DispositionConfig `json:"on_failure,omitempty"`
+
+	ScanOnStart *ScanOnStartConfig `json:"scan_on_start,omitempty"`
+
+	Filters   *FilterConfig    `json:"filters,omitempty"`
+	Readiness *ReadinessConfig `json:"readiness,omitempty"`
+	// Trigger 设置后只在触发文件到达时执行命令，批次为触发文件所在目录中匹配 file_patterns 的全部文件
+	Trigger *TriggerConfig `json:"trigger,omitempty"`
+
+	Env          map[string]string `json:"env,omitempty"`
+	Substitution string            `json:"substitution,omitempty"`
+}
+
+// BatchConfig 批处理配置：目录稳定后将所有匹配文件一次性交给命令
+type BatchConfig struct {
+	Mode           string `json:"mode"`
+	ManifestFormat string `json:"manifest_format,omitempty"`
+	Parallelism    int    `json:"parallelism,omitempty"`
+}
+
+// OutputConfig 命令输出处理配置
+type OutputConfig struct {
+	// ToFile 为 true 时每次执行的输出写入 <dir>/<monitor_id>/<timestamp>-<file>.log
+	ToFile bool   `json:"to_file,omitempty"`
+	Dir    string `json:"dir,omitempty"`
+	// MaxBytes 单次执行写入文件的最大字节数，超出部分丢弃
+	MaxBytes int64 `json:"max_bytes,omitempty"`
+	// TailBytes 内存中保留的输出末尾字节数，失败时附加到错误日志
+	TailBytes int `json:"tail_bytes,omitempty"`
+	// StdoutLevel/StderrLevel 逐行写入日志的级别：debug、info、warn、error 或 none
+	StdoutLevel string `json:"stdout_level,omitempty"`
+	StderrLevel string `json:"stderr_level,omitempty"`
+}
+
+// DispositionConfig 命令执行结束后对触发文件的处置
+type DispositionConfig struct {
+	// Action move、copy、delete、rename（原地加时间戳后缀）或 quarantine
+	Action string `json:"action"`
+	// Target move/copy/quarantine 的目标目录，相对路径相对于监控目录；
+	// quarantine 未设置时为 <directory>/quarantine
+	Target string `json:"target,omitempty"`
+	// TimestampSuffix 为 true 时 move/copy/quarantine 的目标文件名加时间戳后缀
+	TimestampSuffix bool `json:"timestamp_suffix,omitempty"`
+}
+
+// TargetDir 返回 move/copy/quarantine 的目标目录，相对路径相对于监控目录 directory；
+// delete/rename 没有目标目录，返回空
+func (dc *DispositionConfig) TargetDir(directory string) string {
+	if dc == nil {
+		return ""
+	}
+	switch dc.Action {
+	case DispositionMove, DispositionCopy, DispositionQuarantine:
+	default:
+		return ""
+	}
+	target := dc.Target
+	if target == "" {
+		target = DefaultQuarantineDir
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(directory, target)
+	}
+	return filepath.Clean(target)
+}
+
+// CalendarConfig 日期日历：File 每行一个 YYYY-MM-DD 日期，
+// Mode 为 exclude（默认，列出的日期不执行，如公共假日）或 include（只在列出的日期执行）
+type CalendarConfig struct {
+	File string `json:"file"`
+	Mode string `json:"mode,omitempty"`
+}
+
+// DefaultMonitorEvents 未配置 events 时触发命令的事件类型；删除事件需显式配置
+var DefaultMonitorEvents = []model.FileEventType{model.FileCreated, model.FileModified, model.FileRenamed}
+
+// HandlesEvent 判断监控项是否对该类型的事件执行命令
+func (m Monitor) HandlesEvent(t model.FileEventType) bool {
+	if len(m.Events) == 0 {
+		for _, e := range DefaultMonitorEvents {
+			if e == t {
+				return true
+			}
+		}
+		return false
+	}
+	for _, e := range m.Events {
+		if model.FileEventType(e) == t {
+			return true
+		}
+	}
+	return false
+}
+
+// CompilePatterns 编译监控项的文件模式
+func (m Monitor) CompilePatterns() (*pattern.Set, error) {
+	return pattern.Compile(m.FilePatterns, m.CaseInsensitive)
+}
+
+// CompileTriggerPatterns 编译触发文件模式，未配置 trigger 时返回 nil
+func (m Monitor) CompileTriggerPatterns() (*pattern.Set, error) {
+	if m.Trigger == nil {
+		return nil, nil
+	}
+	return pattern.Compile(m.Trigger.Patterns, m.CaseInsensitive)
+}
+
+// IgnorePatterns 返回监控项生效的忽略规则：全局规则（未配置时为默认规则）在前，监控项规则在后
+func (c *Config) IgnorePatterns(monitor Monitor) []string {
+	global := c.Settings.Ignore
+	if global == nil {
+		global = ignore.DefaultPatterns
+	}
+	patterns := make([]string, 0, len(global)+len(monitor.Ignore))
+	patterns = append(patterns, global...)
+	return append(patterns, monitor.Ignore...)
+}
+
+// UnlimitedDepth 不限制子目录层数
+const UnlimitedDepth = -1
+
+// WatchDepth 返回监控项处理的子目录层数：0 只处理顶层，UnlimitedDepth 不限制
+func (m Monitor) WatchDepth() int {
+	if !m.Recursive {
+		return 0
+	}
+	if m.MaxDepth > 0 {
+		return m.MaxDepth
+	}
+	return UnlimitedDepth
+}
+
+// RelPath 返回 path 相对于监控目录的路径（使用 / 分隔），path 不在监控目录之下时返回 false
+func (m Monitor) RelPath(path string) (string, bool) {
+	rel, err := filepath.Rel(m.Directory, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Covers 判断 path 是否位于监控项处理的目录层级内。
+// 监控项自己的处置目标目录（如递归监控下默认的 <directory>/quarantine）中的文件不处理
+func (m Monitor) Covers(path string) bool {
+	rel, ok := m.RelPath(path)
+	if !ok {
+		return false
+	}
+	depth := m.WatchDepth()
+	if depth != UnlimitedDepth && strings.Count(rel, "/") > depth {
+		return false
+	}
+	return !m.InDispositionDir(path)
+}
+
+// DispositionDirs 返回 on_success/on_failure 移动、复制或隔离文件的目标目录
+func (m Monitor) DispositionDirs() []string {
+	var dirs []string
+	for _, dc := range []*DispositionConfig{m.OnSuccess, m.OnFailure} {
+		if dir := dc.TargetDir(m.Directory); dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// InDispositionDir 判断 path 是否为监控项的处置目标目录或位于其中
+func (m Monitor) InDispositionDir(path string) bool {
+	for _, dir := range m.DispositionDirs() {
+		if rel, err := filepath.Rel(dir, path); err == nil && (rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasSchedule 判断监控项是否受调度限制（调度表达式或日历）
+func (m Monitor) HasSchedule() bool {
+	return m.Schedule != "" || m.Calendar != ""
+}
+
+// Location 返回监控项的时区，为空时使用本地时区
+func (m Monitor) Location() (*time.Location, error) {
+	if m.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(m.Timezone)
+}
+
+// LoadCalendars 加载 calendars 中定义的全部日历
+func (c *Config) LoadCalendars() (map[string]*calendar.Calendar, error) {
+	calendars := make(map[string]*calendar.Calendar, len(c.Calendars))
+	for name, cc := range c.Calendars {
+		if strings.TrimSpace(cc.File) == "" {
+			return nil, fmt.Errorf("calendar %s: file cannot be empty", name)
+		}
+		cal, err := calendar.Load(name, cc.File, cc.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %v", name, err)
+		}
+		calendars[name] = cal
+	}
+	return calendars, nil
+}
+
+// ScanOnStartConfig 启动时扫描监控目录中已存在的匹配文件（服务停止期间到达的文件），
+// 作为 created 事件进入正常的聚合流程
+type ScanOnStartConfig struct {
+	// MaxAgeSeconds 只处理修改时间在此时长内的文件，0 不限制
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+	// SkipProcessed 为 true 时跳过处理记录中已成功处理过（路径、大小、修改时间均相同）的文件
+	SkipProcessed bool `json:"skip_processed,omitempty"`
+}
+
+// FilterConfig 执行命令前按文件属性过滤，不满足条件的文件记录原因后跳过；未设置的条件不限制
+type FilterConfig struct {
+	// MinSize/MaxSize 文件大小范围（字节）
+	MinSize int64 `json:"min_size,omitempty"`
+	MaxSize int64 `json:"max_size,omitempty"`
+	// MinAgeSeconds/MaxAgeSeconds 修改时间距今的范围（秒）
+	MinAgeSeconds int `json:"min_age_seconds,omitempty"`
+	MaxAgeSeconds int `json:"max_age_seconds,omitempty"`
+	// UID/GID 文件属主与属组
+	UID *int `json:"uid,omitempty"`
+	GID *int `json:"gid,omitempty"`
+	// ModeRequired 八进制权限位（如 "0004"），必须全部设置；ModeForbidden 不能设置其中任一位
+	ModeRequired  string `json:"mode_required,omitempty"`
+	ModeForbidden string `json:"mode_forbidden,omitempty"`
+	// MIMETypes 允许的内容类型（按文件开头的内容识别），支持 text/* 形式
+	MIMETypes []string `json:"mime_types,omitempty"`
+}
+
+// ReadinessConfig 文件写入完成的判断：静默期结束后按策略检查，未完成的文件等待后再次检查
+type ReadinessConfig struct {
+	// Strategy quiet（默认）、close_write、size_stable、marker 或 exclusive_open
+	Strategy string `json:"strategy"`
+	// Checks size_stable 需要连续不变的检查次数，默认 3
+	Checks int `json:"checks,omitempty"`
+	// IntervalMs 两次检查的间隔（毫秒），默认 settings.min_stability_time_ms
+	IntervalMs int `json:"interval_ms,omitempty"`
+	// MarkerSuffix marker 策略的标记文件后缀，默认 .done；标记文件本身不会触发命令
+	MarkerSuffix string `json:"marker_suffix,omitempty"`
+	// TimeoutSeconds 等待写入完成的最长时间，超时的文件被跳过，默认 3600
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// TriggerConfig 触发文件配置：上游写完一组数据文件后放置 READY、_SUCCESS 等触发文件
+type TriggerConfig struct {
+	// Patterns 触发文件模式，语法同 file_patterns（大小写规则跟随 case_insensitive）
+	Patterns []string `json:"patterns"`
+	// Remove 为 true 时命令执行成功后删除触发文件
+	Remove bool `json:"remove,omitempty"`
+}
+
+// Modes 解析 ModeRequired 与 ModeForbidden
+func (f *FilterConfig) Modes() (required, forbidden os.FileMode, err error) {
+	if required, err = parseMode(f.ModeRequired); err != nil {
+		return 0, 0, fmt.Errorf("invalid mode_required: %v", err)
+	}
+	if forbidden, err = parseMode(f.ModeForbidden); err != nil {
+		return 0, 0, fmt.Errorf("invalid mode_forbidden: %v", err)
+	}
+	return required, forbidden, nil
+}
+
+func parseMode(s string) (os.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, err
+	}
+	if v > 0777 {
+		return 0, fmt.Errorf("%s exceeds permission bits 0777", s)
+	}
+	return os.FileMode(v), nil
+}
+
+// RetryConfig 监控项级别的重试策略，未设置的字段沿用全局 settings
+type RetryConfig struct {
+	Attempts         *int   `json:"attempts,omitempty"`
+	DelaySeconds     *int   `json:"delay_seconds,omitempty"`
+	Backoff          string `json:"backoff,omitempty"`
+	MaxDelaySeconds  int    `json:"max_delay_seconds,omitempty"`
+	MaxWindowSeconds int    `json:"max_window_seconds,omitempty"`
+	RetryOnExitCodes []int  `json:"retry_on_exit_codes,omitempty"`
+	RetryOnTimeout   *bool  `json:"retry_on_timeout,omitempty"`
 }
 
 func (c *Config) Validate() error {
@@ -51,21 +520,117 @@ func (c *Config) Validate() error {
 		return errors.New("at least one monitor must be configured")
 	}
 
+	if _, err := c.LoadCalendars(); err != nil {
+		return err
+	}
+
 	monitorIDs := make(map[string]bool)
 	for _, monitor := range c.Monitors {
 		if monitor.Directory == "" {
 			return errors.New("monitor directory cannot be empty")
 		}
-		if monitor.Command == "" {
-			return errors.New("monitor command cannot be empty")
+		if err := validateCommandForm(monitor); err != nil {
+			return err
 		}
 		if len(monitor.FilePatterns) == 0 {
 			return errors.New("monitor must have at least one file pattern: " + monitor.Directory)
 		}
+		if _, err := monitor.CompilePatterns(); err != nil {
+			return fmt.Errorf("invalid file patterns for monitor %s: %v", monitor.Directory, err)
+		}
 		if monitor.Timeout <= 0 {
 			return errors.New("monitor timeout must be greater than 0: " + monitor.Directory)
 		}
 
+		if err := validateRetryConfig(monitor.Retry); err != nil {
+			return fmt.Errorf("invalid retry configuration for monitor %s: %v", monitor.Directory, err)
+		}
+
+		switch monitor.Watcher {
+		case "", WatcherInotify, WatcherPoll, WatcherAuto:
+		default:
+			return fmt.Errorf("unknown watcher backend for monitor %s: %s", monitor.Directory, monitor.Watcher)
+		}
+
+		if _, err := ignore.Compile(c.IgnorePatterns(monitor)); err != nil {
+			return fmt.Errorf("invalid ignore rules for monitor %s: %v", monitor.Directory, err)
+		}
+
+		for _, e := range monitor.Events {
+			switch model.FileEventType(e) {
+			case model.FileCreated, model.FileModified, model.FileRenamed, model.FileDeleted:
+			default:
+				return fmt.Errorf("unknown event type for monitor %s: %s", monitor.Directory, e)
+			}
+		}
+
+		if monitor.MaxDepth < 0 {
+			return fmt.Errorf("max_depth cannot be negative for monitor %s", monitor.Directory)
+		}
+		if monitor.MaxDepth > 0 && !monitor.Recursive {
+			return fmt.Errorf("max_depth requires recursive for monitor %s", monitor.Directory)
+		}
+
+		if monitor.DebounceSeconds < 0 {
+			return fmt.Errorf("debounce_seconds cannot be negative for monitor %s", monitor.Directory)
+		}
+		if monitor.MaxWaitSeconds < 0 {
+			return fmt.Errorf("max_wait_seconds cannot be negative for monitor %s", monitor.Directory)
+		}
+		switch monitor.DebounceEdge {
+		case "", DebounceTrailing, DebounceLeading, DebounceBoth:
+		default:
+			return fmt.Errorf("unknown debounce_edge for monitor %s: %s", monitor.Directory, monitor.DebounceEdge)
+		}
+
+		switch monitor.OutsideSchedule {
+		case "", OutsideScheduleSkip:
+		case OutsideScheduleDefer:
+			if !monitor.HasSchedule() {
+				return fmt.Errorf("outside_schedule %s requires a schedule or calendar for monitor %s", OutsideScheduleDefer, monitor.Directory)
+			}
+		default:
+			return fmt.Errorf("unknown outside_schedule mode for monitor %s: %s", monitor.Directory, monitor.OutsideSchedule)
+		}
+
+		switch monitor.Substitution {
+		case "", SubstitutionQuote, SubstitutionRaw:
+		default:
+			return fmt.Errorf("unknown substitution mode for monitor %s: %s", monitor.Directory, monitor.Substitution)
+		}
+		if err := validateRawVariables(monitor); err != nil {
+			return fmt.Errorf("invalid command for monitor %s: %v", monitor.Directory, err)
+		}
+
+		if err := validateBatchConfig(monitor.Batch); err != nil {
+			return fmt.Errorf("invalid batch configuration for monitor %s: %v", monitor.Directory, err)
+		}
+
+		if err := validateOutputConfig(monitor.Output); err != nil {
+			return fmt.Errorf("invalid output configuration for monitor %s: %v", monitor.Directory, err)
+		}
+
+		if err := validateFilterConfig(monitor.Filters); err != nil {
+			return fmt.Errorf("invalid filters for monitor %s: %v", monitor.Directory, err)
+		}
+		if err := validateReadinessConfig(monitor.Readiness); err != nil {
+			return fmt.Errorf("invalid readiness for monitor %s: %v", monitor.Directory, err)
+		}
+		if err := validateTriggerConfig(monitor); err != nil {
+			return fmt.Errorf("invalid trigger for monitor %s: %v", monitor.Directory, err)
+		}
+
+		if err := validateDisposition(monitor.OnSuccess); err != nil {
+			return fmt.Errorf("invalid on_success for monitor %s: %v", monitor.Directory, err)
+		}
+		if err := validateDisposition(monitor.OnFailure); err != nil {
+			return fmt.Errorf("invalid on_failure for monitor %s: %v", monitor.Directory, err)
+		}
+
+		if monitor.ScanOnStart != nil && monitor.ScanOnStart.MaxAgeSeconds < 0 {
+			return fmt.Errorf("scan_on_start.max_age_seconds cannot be negative for monitor %s", monitor.Directory)
+		}
+
 		if monitor.ID != "" {
 			if monitorIDs[monitor.ID] {
 				return fmt.Errorf("duplicate monitor ID: %s", monitor.ID)
@@ -74,25 +639,241 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if err := validateRetryBackoff(c.Settings.RetryBackoff); err != nil {
+		return err
+	}
+	switch c.Settings.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("unknown log format: %s", c.Settings.LogFormat)
+	}
+	if c.Settings.RetryAttempts < 0 {
+		return errors.New("retry_attempts cannot be negative")
+	}
+	if c.Settings.RetryMaxDelaySeconds < 0 || c.Settings.RetryMaxWindowSeconds < 0 {
+		return errors.New("retry delay limits cannot be negative")
+	}
+	if c.Settings.HistoryRetentionDays < 0 || c.Settings.HistoryOutputMaxBytes < 0 {
+		return errors.New("history settings cannot be negative")
+	}
+	if c.Settings.JournalCompactThreshold < 0 {
+		return errors.New("journal_compact_threshold cannot be negative")
+	}
+
 	for _, monitor := range c.Monitors {
 		if monitor.Schedule != "" {
 			if err := validateCronExpression(monitor.Schedule); err != nil {
 				return fmt.Errorf("invalid cron expression %s: %v", monitor.Schedule, err)
 			}
 		}
+
+		if _, err := monitor.Location(); err != nil {
+			return fmt.Errorf("invalid timezone for monitor %s: %v", monitor.Directory, err)
+		}
+		if monitor.Calendar != "" {
+			if _, ok := c.Calendars[monitor.Calendar]; !ok {
+				return fmt.Errorf("unknown calendar for monitor %s: %s", monitor.Directory, monitor.Calendar)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateCommandForm 校验 command（shell 形式）与 args（exec 形式）二选一，
+// exec 形式的可执行文件必须存在于 PATH（或为有效路径）
+func validateCommandForm(monitor Monitor) error {
+	hasCommand := strings.TrimSpace(monitor.Command) != ""
+	hasArgs := len(monitor.Args) > 0
+
+	switch {
+	case hasCommand && hasArgs:
+		return errors.New("monitor command and args cannot both be set: " + monitor.Directory)
+	case !hasCommand && !hasArgs:
+		return errors.New("monitor command cannot be empty")
+	case hasCommand:
+		return nil
+	}
+
+	executable := monitor.Args[0]
+	if strings.TrimSpace(executable) == "" {
+		return errors.New("monitor args executable cannot be empty: " + monitor.Directory)
+	}
+	if strings.Contains(executable, "${") {
+		// 可执行文件由变量决定，只能在运行时确定
+		return nil
+	}
+	if !filepath.IsAbs(executable) && strings.ContainsRune(executable, filepath.Separator) {
+		// 相对路径在监控目录下执行
+		executable = filepath.Join(monitor.Directory, executable)
+	}
+	if _, err := exec.LookPath(executable); err != nil {
+		return fmt.Errorf("monitor args executable not found: %s: %v", monitor.Args[0], err)
+	}
+	return nil
+}
+
+// CommandLine 返回用于日志和去重的命令描述
+func (m Monitor) CommandLine() string {
+	if len(m.Args) > 0 {
+		return strings.Join(m.Args, " ")
+	}
+	return m.Command
+}
+
+func validateRetryConfig(rc *RetryConfig) error {
+	if rc == nil {
+		return nil
+	}
+	if rc.Attempts != nil && *rc.Attempts < 0 {
+		return errors.New("retry attempts cannot be negative")
+	}
+	if rc.DelaySeconds != nil && *rc.DelaySeconds < 0 {
+		return errors.New("retry delay cannot be negative")
+	}
+	if rc.MaxDelaySeconds < 0 || rc.MaxWindowSeconds < 0 {
+		return errors.New("retry delay limits cannot be negative")
+	}
+	return validateRetryBackoff(rc.Backoff)
+}
+
+func validateBatchConfig(bc *BatchConfig) error {
+	if bc == nil {
+		return nil
+	}
+	switch bc.Mode {
+	case BatchModeFileList, BatchModeManifest, BatchModeStdin, BatchModePerFile:
+	default:
+		return fmt.Errorf("unknown batch mode: %q", bc.Mode)
+	}
+	switch bc.ManifestFormat {
+	case "", ManifestFormatLines, ManifestFormatJSON:
+	default:
+		return fmt.Errorf("unknown manifest format: %s", bc.ManifestFormat)
+	}
+	if bc.Parallelism < 0 {
+		return errors.New("batch parallelism cannot be negative")
+	}
+	return nil
+}
+
+func validateOutputConfig(oc *OutputConfig) error {
+	if oc == nil {
+		return nil
+	}
+	if oc.MaxBytes < 0 || oc.TailBytes < 0 {
+		return errors.New("output size limits cannot be negative")
+	}
+	for _, level := range []string{oc.StdoutLevel, oc.StderrLevel} {
+		switch level {
+		case "", "debug", "info", "warn", "error", OutputLevelNone:
+		default:
+			return fmt.Errorf("unknown output log level: %s", level)
+		}
 	}
+	return nil
+}
 
+func validateFilterConfig(fc *FilterConfig) error {
+	if fc == nil {
+		return nil
+	}
+	if fc.MinSize < 0 || fc.MaxSize < 0 {
+		return errors.New("size limits cannot be negative")
+	}
+	if fc.MaxSize > 0 && fc.MinSize > fc.MaxSize {
+		return errors.New("min_size cannot be greater than max_size")
+	}
+	if fc.MinAgeSeconds < 0 || fc.MaxAgeSeconds < 0 {
+		return errors.New("age limits cannot be negative")
+	}
+	if fc.MaxAgeSeconds > 0 && fc.MinAgeSeconds > fc.MaxAgeSeconds {
+		return errors.New("min_age_seconds cannot be greater than max_age_seconds")
+	}
+	if _, _, err := fc.Modes(); err != nil {
+		return err
+	}
+	for _, t := range fc.MIMETypes {
+		if !strings.Contains(t, "/") {
+			return fmt.Errorf("invalid mime type %q", t)
+		}
+		if _, err := path.Match(t, ""); err != nil {
+			return fmt.Errorf("invalid mime type %q: %v", t, err)
+		}
+	}
 	return nil
 }
 
+func validateReadinessConfig(rc *ReadinessConfig) error {
+	if rc == nil {
+		return nil
+	}
+	switch rc.Strategy {
+	case "", ReadinessQuiet, ReadinessCloseWrite, ReadinessSizeStable, ReadinessMarker, ReadinessExclusiveOpen:
+	default:
+		return fmt.Errorf("unknown readiness strategy: %s", rc.Strategy)
+	}
+	if rc.Checks < 0 || rc.IntervalMs < 0 || rc.TimeoutSeconds < 0 {
+		return errors.New("checks, interval_ms and timeout_seconds cannot be negative")
+	}
+	if rc.MarkerSuffix != "" && strings.ContainsRune(rc.MarkerSuffix, filepath.Separator) {
+		return fmt.Errorf("marker_suffix cannot contain a path separator: %s", rc.MarkerSuffix)
+	}
+	return nil
+}
+
+func validateTriggerConfig(monitor Monitor) error {
+	if monitor.Trigger == nil {
+		return nil
+	}
+	if len(monitor.Trigger.Patterns) == 0 {
+		return errors.New("at least one trigger pattern is required")
+	}
+	if _, err := monitor.CompileTriggerPatterns(); err != nil {
+		return err
+	}
+	if monitor.Batch != nil && monitor.Batch.Mode == BatchModePerFile {
+		return fmt.Errorf("batch mode %s cannot be used with a trigger", BatchModePerFile)
+	}
+	return nil
+}
+
+func validateDisposition(dc *DispositionConfig) error {
+	if dc == nil {
+		return nil
+	}
+	switch dc.Action {
+	case DispositionMove, DispositionCopy:
+		if strings.TrimSpace(dc.Target) == "" {
+			return fmt.Errorf("action %s requires a target directory", dc.Action)
+		}
+	case DispositionDelete, DispositionRename:
+		if dc.Target != "" {
+			return fmt.Errorf("action %s does not take a target directory", dc.Action)
+		}
+	case DispositionQuarantine:
+	default:
+		return fmt.Errorf("unknown action: %q", dc.Action)
+	}
+	return nil
+}
+
+func validateRetryBackoff(backoff string) error {
+	switch backoff {
+	case "", RetryBackoffFixed, RetryBackoffLinear, RetryBackoffExponential, RetryBackoffJitter:
+		return nil
+	default:
+		return fmt.Errorf("unknown retry backoff: %s", backoff)
+	}
+}
+
 func validateCronExpression(cron string) error {
 	if cron == "" {
 		return nil
 	}
 
-	parts := strings.Fields(cron)
-	if len(parts) != 5 {
-		return errors.New("cron expression must contain 5 fields")
+	if !gronx.IsValid(cron) {
+		return errors.New("cron expression is not valid")
 	}
 
 	return nil
@@ -155,18 +936,46 @@ func applyDefaults(cfg *Config) {
 		cfg.Settings.DirectoryStabilityTimeoutSeconds = DefaultDirectoryStabilityTimeoutSeconds
 	}
 
-	if cfg.Settings.RetryAttempts <= 0 {
-		cfg.Settings.RetryAttempts = DefaultRetryAttempts
-	}
-
 	if cfg.Settings.RetryDelaySeconds <= 0 {
 		cfg.Settings.RetryDelaySeconds = DefaultRetryDelaySeconds
 	}
 
+	if cfg.Settings.RetryBackoff == "" {
+		cfg.Settings.RetryBackoff = DefaultRetryBackoff
+	}
+
+	if cfg.Settings.RetryMaxDelaySeconds <= 0 {
+		cfg.Settings.RetryMaxDelaySeconds = DefaultRetryMaxDelaySeconds
+	}
+
 	if cfg.Settings.HealthCheckIntervalSeconds <= 0 {
 		cfg.Settings.HealthCheckIntervalSeconds = DefaultHealthCheckIntervalSeconds
 	}
 
+	if cfg.Settings.HealthErrorThreshold <= 0 {
+		cfg.Settings.HealthErrorThreshold = DefaultHealthErrorThreshold
+	}
+
+	if cfg.Settings.DataDir == "" {
+		cfg.Settings.DataDir = DefaultDataDir
+	}
+
+	if cfg.Settings.HistoryRetentionDays <= 0 {
+		cfg.Settings.HistoryRetentionDays = DefaultHistoryRetentionDays
+	}
+
+	if cfg.Settings.HistoryOutputMaxBytes <= 0 {
+		cfg.Settings.HistoryOutputMaxBytes = DefaultHistoryOutputMaxBytes
+	}
+
+	if cfg.Settings.JournalCompactThreshold <= 0 {
+		cfg.Settings.JournalCompactThreshold = DefaultJournalCompactThreshold
+	}
+
+	if cfg.Settings.PollIntervalMs <= 0 {
+		cfg.Settings.PollIntervalMs = DefaultPollIntervalMs
+	}
+
 	if cfg.Settings.LogMaxBackups <= 0 {
 		cfg.Settings.LogMaxBackups = DefaultLogMaxBackups
 	}
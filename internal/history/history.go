@@ -0,0 +1,330 @@
+// Package history 持久化命令执行记录。
+//
+// 记录以 JSON Lines 追加写入 <dir>/history-YYYY-MM-DD.jsonl，按执行开始日期分段；
+// 段文件名即按天的时间索引，查询时只读取时间范围内的段，保留期清理按段整体删除。
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// 段文件名前缀与后缀
+	SegmentPrefix = "history-"
+	SegmentSuffix = ".jsonl"
+	// 段文件名中的日期格式
+	SegmentDateFormat = "2006-01-02"
+
+	DefaultDirPerm  = 0755
+	DefaultFilePerm = 0644
+
+	// 单行记录读取上限
+	maxRecordLineSize = 4 * 1024 * 1024
+)
+
+// 执行状态
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusTimeout = "timeout"
+)
+
+// Record 一次命令执行（一次尝试）的记录
+type Record struct {
+	MonitorID string    `json:"monitor_id"`
+	Paths     []string  `json:"paths"`
+	EventType string    `json:"event_type"`
+	Attempt   int       `json:"attempt"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Status    string    `json:"status"`
+	ExitCode  int       `json:"exit_code"`
+	TimedOut  bool      `json:"timed_out,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Stdout    string    `json:"stdout,omitempty"`
+	Stderr    string    `json:"stderr,omitempty"`
+	// OutputFile 完整输出文件路径（启用 output.to_file 时）
+	OutputFile string `json:"output_file,omitempty"`
+}
+
+// Duration 执行耗时
+func (r Record) Duration() time.Duration {
+	return r.End.Sub(r.Start)
+}
+
+// Filter 查询条件，零值字段不参与过滤
+type Filter struct {
+	MonitorID string
+	// PathGlob 与任一触发路径（完整路径或文件名）匹配即可
+	PathGlob string
+	Status   string
+	Since    time.Time
+	Until    time.Time
+	// Limit 最多返回的记录数（按开始时间倒序），<=0 不限制
+	Limit int
+}
+
+// Match 判断记录是否满足过滤条件
+func (f Filter) Match(r Record) bool {
+	if f.MonitorID != "" && r.MonitorID != f.MonitorID {
+		return false
+	}
+	if f.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if !f.Since.IsZero() && r.Start.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Start.After(f.Until) {
+		return false
+	}
+	if f.PathGlob != "" {
+		matched := false
+		for _, p := range r.Paths {
+			if ok, _ := filepath.Match(f.PathGlob, p); ok {
+				matched = true
+				break
+			}
+			if ok, _ := filepath.Match(f.PathGlob, filepath.Base(p)); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Store 执行记录存储
+type Store struct {
+	dir       string
+	retention time.Duration
+	maxOutput int
+
+	mu      sync.Mutex
+	file    *os.File
+	fileDay string
+}
+
+// Open 打开（必要时创建）dir 下的执行记录存储。
+// retention<=0 表示不清理；maxOutput>0 时 stdout/stderr 只保留最后 maxOutput 字节。
+func Open(dir string, retention time.Duration, maxOutput int) (*Store, error) {
+	if err := os.MkdirAll(dir, DefaultDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	return &Store{
+		dir:       dir,
+		retention: retention,
+		maxOutput: maxOutput,
+	}, nil
+}
+
+// Dir 存储目录
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Append 追加一条记录
+func (s *Store) Append(rec Record) error {
+	rec.Stdout = TruncateOutput(rec.Stdout, s.maxOutput)
+	rec.Stderr = TruncateOutput(rec.Stderr, s.maxOutput)
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %v", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := rec.Start.Local().Format(SegmentDateFormat)
+	if s.file == nil || s.fileDay != day {
+		if s.file != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		f, err := os.OpenFile(s.segmentPath(day), os.O_CREATE|os.O_WRONLY|os.O_APPEND, DefaultFilePerm)
+		if err != nil {
+			return fmt.Errorf("failed to open history segment: %v", err)
+		}
+		s.file = f
+		s.fileDay = day
+	}
+
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write history record: %v", err)
+	}
+	return nil
+}
+
+// Query 按条件查询记录，结果按开始时间倒序
+func (s *Store) Query(f Filter) ([]Record, error) {
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	// 从最新的段开始读取，满足 Limit 后即可停止
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+		if !f.Since.IsZero() && seg.day.AddDate(0, 0, 1).Before(f.Since) {
+			break
+		}
+		if !f.Until.IsZero() && seg.day.After(f.Until) {
+			continue
+		}
+
+		segRecords, err := readSegment(seg.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, rec := range segRecords {
+			if f.Match(rec) {
+				records = append(records, rec)
+			}
+		}
+		// 较早的段只包含更早的记录
+		if f.Limit > 0 && len(records) >= f.Limit {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Start.After(records[j].Start) })
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[:f.Limit]
+	}
+	return records, nil
+}
+
+// Prune 删除超过保留期的段，返回删除的段数
+func (s *Store) Prune(now time.Time) (int, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	segments, err := s.segments()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := now.Add(-s.retention)
+	removed := 0
+	for _, seg := range segments {
+		// 段内最晚的记录也早于保留期时整体删除
+		if !seg.day.AddDate(0, 0, 1).Before(cutoff) {
+			continue
+		}
+		s.mu.Lock()
+		if s.file != nil && s.fileDay == seg.name {
+			s.file.Close()
+			s.file = nil
+			s.fileDay = ""
+		}
+		err := os.Remove(seg.path)
+		s.mu.Unlock()
+		if err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove history segment %s: %v", seg.path, err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Close 关闭当前写入的段
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	s.fileDay = ""
+	return err
+}
+
+func (s *Store) segmentPath(day string) string {
+	return filepath.Join(s.dir, SegmentPrefix+day+SegmentSuffix)
+}
+
+type segment struct {
+	name string
+	path string
+	day  time.Time
+}
+
+// segments 返回按日期升序排列的段
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history directory: %v", err)
+	}
+
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, SegmentPrefix) || !strings.HasSuffix(name, SegmentSuffix) {
+			continue
+		}
+		dayStr := strings.TrimSuffix(strings.TrimPrefix(name, SegmentPrefix), SegmentSuffix)
+		day, err := time.ParseInLocation(SegmentDateFormat, dayStr, time.Local)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{name: dayStr, path: filepath.Join(s.dir, name), day: day})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].day.Before(segments[j].day) })
+	return segments, nil
+}
+
+// readSegment 读取一个段的全部记录，跳过无法解析的行（如写入中断的最后一行）
+func readSegment(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history segment: %v", err)
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordLineSize)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history segment %s: %v", path, err)
+	}
+	return records, nil
+}
+
+// TruncateOutput 保留输出的最后 max 字节，max<=0 时不截断
+func TruncateOutput(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	start := len(s) - max
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	return fmt.Sprintf("...(truncated %d bytes)\n", start) + s[start:]
+}
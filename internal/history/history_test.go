@@ -0,0 +1,104 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendQuery(t *testing.T) {
+	store, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	records := []Record{
+		{MonitorID: "a", Paths: []string{"/in/x.csv"}, Start: day1, End: day1.Add(time.Second), Status: StatusSuccess},
+		{MonitorID: "a", Paths: []string{"/in/y.txt"}, Start: day1.Add(time.Hour), End: day1.Add(time.Hour), Status: StatusFailure, ExitCode: 1},
+		{MonitorID: "b", Paths: []string{"/in/z.csv"}, Start: day2, End: day2, Status: StatusTimeout, TimedOut: true},
+	}
+	for _, rec := range records {
+		if err := store.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string // 期望的第一个路径，按开始时间倒序
+	}{
+		{"all", Filter{}, []string{"/in/z.csv", "/in/y.txt", "/in/x.csv"}},
+		{"monitor", Filter{MonitorID: "a"}, []string{"/in/y.txt", "/in/x.csv"}},
+		{"glob base name", Filter{PathGlob: "*.csv"}, []string{"/in/z.csv", "/in/x.csv"}},
+		{"glob full path", Filter{PathGlob: "/in/y.*"}, []string{"/in/y.txt"}},
+		{"status", Filter{Status: StatusTimeout}, []string{"/in/z.csv"}},
+		{"since", Filter{Since: day1.Add(30 * time.Minute)}, []string{"/in/z.csv", "/in/y.txt"}},
+		{"until", Filter{Until: day1.Add(30 * time.Minute)}, []string{"/in/x.csv"}},
+		{"limit", Filter{Limit: 1}, []string{"/in/z.csv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.Query(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, rec := range got {
+				paths = append(paths, rec.Paths[0])
+			}
+			if strings.Join(paths, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("got %v, want %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 7*24*time.Hour, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.Local)
+	for _, start := range []time.Time{now.AddDate(0, 0, -10), now.AddDate(0, 0, -7), now} {
+		if err := store.Append(Record{MonitorID: "a", Start: start, End: start}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := store.Prune(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("removed %d segments, want 1", removed)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history-2026-03-10.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("expired segment still present: %v", err)
+	}
+	if got, _ := store.Query(Filter{}); len(got) != 2 {
+		t.Errorf("got %d records after prune, want 2", len(got))
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	if got := TruncateOutput("short", 10); got != "short" {
+		t.Errorf("got %q", got)
+	}
+	got := TruncateOutput("0123456789abcdef", 6)
+	if !strings.HasSuffix(got, "abcdef") || !strings.Contains(got, "truncated 10 bytes") {
+		t.Errorf("got %q", got)
+	}
+	// 不在多字节字符中间截断
+	if got := TruncateOutput("错误信息", 5); !strings.HasSuffix(got, "息") || strings.Contains(got, "�") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,165 @@
+// Package ignore gitignore 风格的忽略规则。
+//
+// 规则按顺序匹配，后面的规则覆盖前面的规则：
+//   - 空行与 # 开头的行被忽略，\# 与 \! 表示字面量
+//   - ! 开头的规则取反（重新包含之前被忽略的路径）
+//   - / 结尾的规则只匹配目录
+//   - 不含 /（结尾的 / 除外）的规则匹配任意层级的名称，否则相对基准目录匹配
+//   - 支持 *、?、[...] 与 **（任意层级目录）
+//
+// 与 gitignore 相同，父目录被忽略时其下的路径无法被重新包含。
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"dir-monitor-go/internal/pattern"
+)
+
+// FileName 监控目录中可选的忽略规则文件
+const FileName = ".dirmonitorignore"
+
+// DefaultPatterns 未配置 settings.ignore 时使用的规则：隐藏文件、编辑器与临时文件、交换文件、锁文件与备份文件
+var DefaultPatterns = []string{".*", "*~", "*.tmp", "*.swp", "*.swo", "*.swn", "*.lock", "*.bak"}
+
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Matcher 编译后的忽略规则
+type Matcher struct {
+	rules []rule
+}
+
+// Compile 按顺序编译规则
+func Compile(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, p := range patterns {
+		r, ok, err := compileRule(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ignore pattern %q: %v", p, err)
+		}
+		if ok {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return m, nil
+}
+
+// ReadFile 读取忽略规则文件的每一行，文件不存在时返回 nil
+func ReadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// Len 返回有效规则数
+func (m *Matcher) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.rules)
+}
+
+// Ignored 判断 base 下的 path 是否被忽略；不在 base 下的路径不被忽略
+func (m *Matcher) Ignored(base, path string, isDir bool) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	return m.Match(filepath.ToSlash(rel), isDir)
+}
+
+// Match 判断以 / 分隔的相对路径是否被忽略
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	// 父目录被忽略时，其下的路径一律忽略
+	for i := 0; i < len(rel); i++ {
+		if rel[i] == '/' && m.matchOne(rel[:i], true) {
+			return true
+		}
+	}
+	return m.matchOne(rel, isDir)
+}
+
+func (m *Matcher) matchOne(rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// compileRule 将一行规则转换为正则表达式；ok 为 false 表示空行或注释
+func compileRule(line string) (r rule, ok bool, err error) {
+	p := strings.TrimRight(line, " \t")
+	if strings.HasSuffix(p, `\`) && len(p) < len(line) {
+		// "\ " 结尾保留一个空格
+		p += " "
+	}
+	if p == "" || strings.HasPrefix(p, "#") {
+		return rule{}, false, nil
+	}
+
+	r.pattern = line
+	switch {
+	case strings.HasPrefix(p, "!"):
+		r.negate = true
+		p = p[1:]
+	case strings.HasPrefix(p, `\!`), strings.HasPrefix(p, `\#`):
+		p = p[1:]
+	}
+	if strings.HasSuffix(p, "/") {
+		r.dirOnly = true
+		p = strings.TrimRight(p, "/")
+	}
+	if p == "" {
+		return rule{}, false, fmt.Errorf("empty pattern")
+	}
+
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if !anchored && !strings.HasPrefix(p, "**") {
+		p = "**/" + p
+	}
+
+	expr, err := pattern.GlobToRegexp(p)
+	if err != nil {
+		return rule{}, false, err
+	}
+	r.re, err = regexp.Compile(expr)
+	if err != nil {
+		return rule{}, false, err
+	}
+	return r, true, nil
+}
@@ -0,0 +1,65 @@
+package ignore
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"default dotfile", DefaultPatterns, ".hidden", false, true},
+		{"default lock", DefaultPatterns, "data.lock", false, true},
+		{"default swap", DefaultPatterns, "x.swp", false, true},
+		{"default keeps csv", DefaultPatterns, "data.csv", false, false},
+		{"default nested", DefaultPatterns, "sub/data.tmp", false, true},
+		{"negation", append(DefaultPatterns, "!*.lock"), "ready.lock", false, false},
+		{"negation later rule wins", []string{"!*.lock", "*.lock"}, "ready.lock", false, true},
+		{"extra extension", []string{"*.filepart", "*.part"}, "upload.csv.filepart", false, true},
+		{"dir only matches dir", []string{"tmp/"}, "tmp", true, true},
+		{"dir only skips file", []string{"tmp/"}, "tmp", false, false},
+		{"files under ignored dir", []string{"tmp/"}, "a/tmp/x.csv", false, true},
+		{"parent cannot be re-included", []string{"tmp/", "!tmp/keep.csv"}, "tmp/keep.csv", false, true},
+		{"anchored", []string{"/in.csv"}, "sub/in.csv", false, false},
+		{"anchored root", []string{"/in.csv"}, "in.csv", false, true},
+		{"path pattern", []string{"archive/*.csv"}, "archive/a.csv", false, true},
+		{"path pattern depth", []string{"archive/*.csv"}, "archive/x/a.csv", false, false},
+		{"double star", []string{"logs/**/*.log"}, "logs/a/b/c.log", false, true},
+		{"double star zero dirs", []string{"logs/**/*.log"}, "logs/c.log", false, true},
+		{"trailing double star", []string{"cache/**"}, "cache/a/b", false, true},
+		{"char class", []string{"file[0-9].txt"}, "file7.txt", false, true},
+		{"negated class", []string{"file[!0-9].txt"}, "file7.txt", false, false},
+		{"comment and blank", []string{"# note", "", "*.bak"}, "a.bak", false, true},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"no rules", nil, ".hidden", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Compile(tt.patterns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoredOutsideBase(t *testing.T) {
+	m, _ := Compile([]string{"*.csv"})
+	if m.Ignored("/data/in", "/data/other/a.csv", false) {
+		t.Error("paths outside the base directory should not be ignored")
+	}
+	if !m.Ignored("/data/in", "/data/in/a.csv", false) {
+		t.Error("path inside the base directory should be ignored")
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	if _, err := Compile([]string{"file[0-9.txt"}); err == nil {
+		t.Error("unterminated character class should be rejected")
+	}
+}
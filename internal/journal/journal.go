@@ -0,0 +1,401 @@
+// Package journal 文件事件的预写日志（WAL）。
+//
+// 事件在进入内存处理流程前追加写入 <dir>/events.jsonl 并 fsync，对应命令执行结束后写入确认记录；
+// 未确认的事件在启动时重放，内存通道已满时暂存在日志中稍后再投递。
+// 内存中只保存未确认事件的序号及其在日志中的位置，取回时按位置从日志读取；
+// 确认记录累计到阈值后压缩：原子重写日志，只保留未确认的事件。
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// FileName 日志文件名
+	FileName = "events.jsonl"
+
+	DefaultDirPerm  = 0755
+	DefaultFilePerm = 0644
+
+	// DefaultCompactThreshold 默认在累计多少条确认记录后压缩
+	DefaultCompactThreshold = 1000
+)
+
+// eventRecord 事件在日志中的表示
+type eventRecord struct {
+	Type      model.FileEventType `json:"type"`
+	Path      string              `json:"path"`
+	OldPath   string              `json:"old_path,omitempty"`
+	Directory string              `json:"directory"`
+	Timestamp time.Time           `json:"timestamp"`
+	Size      int64               `json:"size,omitempty"`
+	ModTime   time.Time           `json:"mtime,omitempty"`
+}
+
+// record 日志中的一行：事件或确认
+type record struct {
+	Seq   uint64       `json:"seq"`
+	Ack   bool         `json:"ack,omitempty"`
+	Event *eventRecord `json:"event,omitempty"`
+}
+
+// pendingEvent 未确认的事件记录在日志中的位置；delivered 为 true 表示已投递到内存处理流程
+type pendingEvent struct {
+	offset    int64
+	length    int
+	delivered bool
+}
+
+// Journal 事件预写日志
+type Journal struct {
+	path             string
+	compactThreshold int
+
+	mu   sync.Mutex
+	file *os.File
+	// reader 按位置读取事件记录，压缩后重新打开
+	reader *os.File
+	// size 日志当前长度，即下一条记录的位置
+	size    int64
+	nextSeq uint64
+	// pending 未确认的事件
+	pending map[uint64]*pendingEvent
+	// acked 上次压缩后写入的确认记录数
+	acked int
+}
+
+// Open 打开（必要时创建）dir 下的日志并压缩。
+// 已有的未确认事件均视为未投递，可通过 Undelivered 读取重放。
+func Open(dir string, compactThreshold int) (*Journal, error) {
+	if err := os.MkdirAll(dir, DefaultDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %v", err)
+	}
+	if compactThreshold <= 0 {
+		compactThreshold = DefaultCompactThreshold
+	}
+
+	j := &Journal{
+		path:             filepath.Join(dir, FileName),
+		compactThreshold: compactThreshold,
+		nextSeq:          1,
+		pending:          make(map[uint64]*pendingEvent),
+	}
+	if err := j.readPending(); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.compactLocked(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Append 持久化事件并返回其序号。事件视为已投递；投递失败时调用 Release。
+func (j *Journal) Append(event model.FileEvent) (uint64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return 0, fmt.Errorf("journal is closed")
+	}
+
+	seq := j.nextSeq
+	offset, length, err := j.writeLocked(record{Seq: seq, Event: toRecord(event)}, true)
+	if err != nil {
+		return 0, err
+	}
+	j.nextSeq++
+	j.pending[seq] = &pendingEvent{offset: offset, length: length, delivered: true}
+	return seq, nil
+}
+
+// Release 将事件标记为未投递（内存通道已满），稍后由 Undelivered 取回
+func (j *Journal) Release(seq uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if p, ok := j.pending[seq]; ok {
+		p.delivered = false
+	}
+}
+
+// Ack 确认事件已处理完成；确认记录达到阈值时压缩日志
+func (j *Journal) Ack(seqs ...uint64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.file == nil {
+		return fmt.Errorf("journal is closed")
+	}
+
+	for _, seq := range seqs {
+		if _, ok := j.pending[seq]; !ok {
+			continue
+		}
+		// 确认记录丢失只会导致事件重放，无需 fsync
+		if _, _, err := j.writeLocked(record{Seq: seq, Ack: true}, false); err != nil {
+			return err
+		}
+		delete(j.pending, seq)
+		j.acked++
+	}
+
+	if j.acked >= j.compactThreshold {
+		return j.compactLocked()
+	}
+	return nil
+}
+
+// Undelivered 按序号顺序返回最多 limit 个未投递的事件并将其标记为已投递；limit<=0 不限制
+func (j *Journal) Undelivered(limit int) ([]model.FileEvent, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.reader == nil {
+		return nil, fmt.Errorf("journal is closed")
+	}
+
+	var waiting []uint64
+	for seq, p := range j.pending {
+		if !p.delivered {
+			waiting = append(waiting, seq)
+		}
+	}
+	sort.Slice(waiting, func(a, b int) bool { return waiting[a] < waiting[b] })
+	if limit > 0 && len(waiting) > limit {
+		waiting = waiting[:limit]
+	}
+
+	events := make([]model.FileEvent, 0, len(waiting))
+	for _, seq := range waiting {
+		rec, err := j.readRecordLocked(j.reader, j.pending[seq])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, fromRecord(rec))
+	}
+	for _, seq := range waiting {
+		j.pending[seq].delivered = true
+	}
+	return events, nil
+}
+
+// Pending 返回未确认的事件数
+func (j *Journal) Pending() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.pending)
+}
+
+// Compact 立即压缩日志
+func (j *Journal) Compact() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.compactLocked()
+}
+
+// Close 关闭日志
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.reader != nil {
+		j.reader.Close()
+		j.reader = nil
+	}
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// writeLocked 追加一条记录，返回记录在日志中的位置与长度（不含换行）
+func (j *Journal) writeLocked(rec record, sync bool) (int64, int, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to encode journal record: %v", err)
+	}
+	offset := j.size
+	n, err := j.file.Write(append(data, '\n'))
+	j.size += int64(n)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to write journal record: %v", err)
+	}
+	if sync {
+		if err := j.file.Sync(); err != nil {
+			return 0, 0, fmt.Errorf("failed to sync journal: %v", err)
+		}
+	}
+	return offset, len(data), nil
+}
+
+// readRecordLocked 从 f 中按位置读取事件记录；调用方持有 mu
+func (j *Journal) readRecordLocked(f *os.File, p *pendingEvent) (record, error) {
+	data := make([]byte, p.length)
+	if _, err := f.ReadAt(data, p.offset); err != nil {
+		return record{}, fmt.Errorf("failed to read journal record: %v", err)
+	}
+	var rec record
+	if err := json.Unmarshal(data, &rec); err != nil || rec.Event == nil {
+		return record{}, fmt.Errorf("corrupt journal record at offset %d", p.offset)
+	}
+	return rec, nil
+}
+
+// readPending 打开日志时记录其中未确认事件的位置；跳过无法解析的行（如写入中断的最后一行）。
+func (j *Journal) readPending() error {
+	f, err := os.Open(j.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	defer f.Close()
+
+	var offset int64
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read journal: %v", err)
+		}
+		data := bytes.TrimSuffix(line, []byte{'\n'})
+		var rec record
+		if json.Unmarshal(data, &rec) == nil {
+			switch {
+			case rec.Ack:
+				delete(j.pending, rec.Seq)
+			case rec.Event != nil:
+				j.pending[rec.Seq] = &pendingEvent{offset: offset, length: len(data)}
+				if rec.Seq >= j.nextSeq {
+					j.nextSeq = rec.Seq + 1
+				}
+			}
+		}
+		offset += int64(len(line))
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// compactLocked 原子重写日志，按序号顺序只保留未确认的事件记录，并重新打开用于追加
+func (j *Journal) compactLocked() error {
+	seqs := make([]uint64, 0, len(j.pending))
+	for seq := range j.pending {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(a, b int) bool { return seqs[a] < seqs[b] })
+
+	source := j.reader
+	if source == nil && len(seqs) > 0 {
+		// 打开日志时尚未建立读取句柄
+		f, err := os.Open(j.path)
+		if err != nil {
+			return fmt.Errorf("failed to compact journal: %v", err)
+		}
+		defer f.Close()
+		source = f
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), "."+FileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	// 新位置在重命名成功后才生效
+	moved := make(map[uint64]pendingEvent, len(seqs))
+	var offset int64
+	w := bufio.NewWriter(tmp)
+	for _, seq := range seqs {
+		p := j.pending[seq]
+		data := make([]byte, p.length)
+		if _, err := source.ReadAt(data, p.offset); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact journal: %v", err)
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact journal: %v", err)
+		}
+		moved[seq] = pendingEvent{offset: offset, length: p.length, delivered: p.delivered}
+		offset += int64(p.length) + 1
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("failed to compact journal: %v", err)
+	}
+	for seq, p := range moved {
+		*j.pending[seq] = p
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	if j.reader != nil {
+		j.reader.Close()
+	}
+	j.file, j.reader = nil, nil
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, DefaultFilePerm)
+	if err != nil {
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	reader, err := os.Open(j.path)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open journal: %v", err)
+	}
+	j.file, j.reader = f, reader
+	j.size = offset
+	j.acked = 0
+	return nil
+}
+
+func toRecord(event model.FileEvent) *eventRecord {
+	return &eventRecord{
+		Type:      event.Type,
+		Path:      event.Path,
+		OldPath:   event.OldPath,
+		Directory: event.Directory,
+		Timestamp: event.Timestamp,
+		Size:      event.Size,
+		ModTime:   event.ModTime,
+	}
+}
+
+func fromRecord(rec record) model.FileEvent {
+	e := rec.Event
+	return model.FileEvent{
+		Type:      e.Type,
+		Path:      e.Path,
+		OldPath:   e.OldPath,
+		Directory: e.Directory,
+		Timestamp: e.Timestamp,
+		Size:      e.Size,
+		ModTime:   e.ModTime,
+		Seq:       rec.Seq,
+	}
+}
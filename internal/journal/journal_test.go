@@ -0,0 +1,185 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dir-monitor-go/internal/model"
+)
+
+func event(name string) model.FileEvent {
+	return model.FileEvent{Type: model.FileCreated, Path: "/data/" + name, Directory: "/data"}
+}
+
+func TestJournalReplayUnacked(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := j.Append(event("a.csv"))
+	b, _ := j.Append(event("b.csv"))
+	c, _ := j.Append(event("c.csv"))
+	if err := j.Ack(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// 已投递的事件不会再次返回
+	if got, _ := j.Undelivered(0); len(got) != 0 {
+		t.Fatalf("undelivered = %v, want none", got)
+	}
+	j.Release(c)
+	got, _ := j.Undelivered(0)
+	if len(got) != 1 || got[0].Seq != c || got[0].Path != "/data/c.csv" {
+		t.Fatalf("undelivered after release = %+v", got)
+	}
+	j.Close()
+
+	j, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	got, _ = j.Undelivered(0)
+	if len(got) != 2 || got[0].Seq != a || got[1].Seq != c {
+		t.Fatalf("replayed = %+v, want seq %d and %d", got, a, c)
+	}
+	if next, _ := j.Append(event("d.csv")); next <= c {
+		t.Errorf("sequence restarted: got %d after %d", next, c)
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	var seqs []uint64
+	for _, name := range []string{"a", "b", "c", "d"} {
+		seq, err := j.Append(event(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, seq)
+	}
+	if err := j.Ack(seqs[:3]...); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "/data/d") {
+		t.Errorf("journal after compaction = %q", data)
+	}
+	if j.Pending() != 1 {
+		t.Errorf("pending = %d, want 1", j.Pending())
+	}
+}
+
+func TestJournalUndeliveredOrder(t *testing.T) {
+	j, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+
+	var seqs []uint64
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		seq, err := j.Append(event(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, seq)
+	}
+	for _, i := range []int{4, 0, 3, 1, 2} {
+		j.Release(seqs[i])
+	}
+
+	var got []uint64
+	for {
+		events, err := j.Undelivered(2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) == 0 {
+			break
+		}
+		for _, e := range events {
+			got = append(got, e.Seq)
+		}
+	}
+	if len(got) != len(seqs) {
+		t.Fatalf("undelivered = %v, want %v", got, seqs)
+	}
+	for i := range seqs {
+		if got[i] != seqs[i] {
+			t.Fatalf("undelivered = %v, want %v", got, seqs)
+		}
+	}
+}
+
+func TestJournalReadsEventsAtRecordedOffsets(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seqs := make(map[string]uint64)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		seq, err := j.Append(event(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seqs[name] = seq
+	}
+	// 第二个确认触发压缩，c 与 d 的记录移动到新位置
+	if err := j.Ack(seqs["a"], seqs["b"]); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := j.Append(event("e"))
+	for _, seq := range []uint64{seqs["c"], seqs["d"], e} {
+		j.Release(seq)
+	}
+	got, err := j.Undelivered(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, ev := range got {
+		paths = append(paths, ev.Path)
+	}
+	if strings.Join(paths, ",") != "/data/c,/data/d,/data/e" {
+		t.Fatalf("undelivered = %v", paths)
+	}
+	j.Close()
+
+	// 写入中断的最后一行被跳过，其余记录按位置读取
+	f, err := os.OpenFile(filepath.Join(dir, FileName), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":99,"event":{"type":"cre`)
+	f.Close()
+
+	j, err = Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	got, err = j.Undelivered(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Path != "/data/c" || got[2].Seq != e || got[2].Path != "/data/e" {
+		t.Fatalf("replayed = %+v", got)
+	}
+}
@@ -0,0 +1,191 @@
+// Package ledger 记录已被成功处理的文件（监控项、路径、大小、修改时间），
+// 启动扫描据此跳过服务停止前已处理过的文件。
+//
+// 记录以 JSON Lines 追加写入 <dir>/processed.jsonl。Open 时加载全部记录并压缩：
+// 同一 (监控项, 路径) 只保留最后一条，文件已不存在或已变化的记录被丢弃。
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// FileName 记录文件名
+	FileName = "processed.jsonl"
+
+	DefaultDirPerm  = 0755
+	DefaultFilePerm = 0644
+)
+
+// Entry 一个已处理文件
+type Entry struct {
+	MonitorID   string    `json:"monitor_id"`
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mtime"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+type entryKey struct {
+	monitorID string
+	path      string
+}
+
+// Store 已处理文件记录
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[entryKey]Entry
+	file    *os.File
+}
+
+// Open 打开（必要时创建）dir 下的记录文件，加载并压缩已有记录
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, DefaultDirPerm); err != nil {
+		return nil, fmt.Errorf("failed to create ledger directory: %v", err)
+	}
+
+	s := &Store{
+		path:    filepath.Join(dir, FileName),
+		entries: make(map[entryKey]Entry),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, DefaultFilePerm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ledger: %v", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+// Contains 判断文件在当前大小与修改时间下是否已被该监控项处理过
+func (s *Store) Contains(monitorID, path string, size int64, modTime time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[entryKey{monitorID, path}]
+	return ok && e.Size == size && e.ModTime.Equal(modTime)
+}
+
+// Add 记录一个已处理文件
+func (s *Store) Add(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode ledger entry: %v", err)
+	}
+	data = append(data, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("ledger is closed")
+	}
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write ledger entry: %v", err)
+	}
+	s.entries[entryKey{e.MonitorID, e.Path}] = e
+	return nil
+}
+
+// Len 返回记录数
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Close 关闭记录文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// load 读取记录文件，跳过无法解析的行（如写入中断的最后一行）
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open ledger: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		s.entries[entryKey{e.MonitorID, e.Path}] = e
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read ledger: %v", err)
+	}
+	return nil
+}
+
+// compact 丢弃已失效的记录并原子重写记录文件
+func (s *Store) compact() error {
+	for key, e := range s.entries {
+		info, err := os.Stat(e.Path)
+		if err != nil || info.Size() != e.Size || !info.ModTime().Equal(e.ModTime) {
+			delete(s.entries, key)
+		}
+	}
+
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ProcessedAt.Before(entries[j].ProcessedAt) })
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+FileName+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact ledger: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to compact ledger: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact ledger: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to compact ledger: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to compact ledger: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to compact ledger: %v", err)
+	}
+	return nil
+}
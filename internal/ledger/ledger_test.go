@@ -0,0 +1,63 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLedgerContainsAndCompact(t *testing.T) {
+	dir := t.TempDir()
+	files := t.TempDir()
+
+	keep := filepath.Join(files, "keep.csv")
+	changed := filepath.Join(files, "changed.csv")
+	gone := filepath.Join(files, "gone.csv")
+	for _, p := range []string{keep, changed, gone} {
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, p := range []string{keep, changed, gone} {
+		info, _ := os.Stat(p)
+		if err := s.Add(Entry{MonitorID: "m1", Path: p, Size: info.Size(), ModTime: info.ModTime(), ProcessedAt: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, _ := os.Stat(keep)
+	if !s.Contains("m1", keep, info.Size(), info.ModTime()) {
+		t.Error("entry should be found")
+	}
+	if s.Contains("m2", keep, info.Size(), info.ModTime()) {
+		t.Error("entry of another monitor should not match")
+	}
+	if s.Contains("m1", keep, info.Size()+1, info.ModTime()) {
+		t.Error("entry with different size should not match")
+	}
+	s.Close()
+
+	if err := os.WriteFile(changed, []byte("new content"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(gone)
+
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 1 {
+		t.Errorf("entries after compaction = %d, want 1", s.Len())
+	}
+	if !s.Contains("m1", keep, info.Size(), info.ModTime()) {
+		t.Error("unchanged entry should survive compaction")
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field Typed key/value pair attached to a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String String field
+func String(key, value string) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int Integer field
+func Int(key string, value int) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Int64 64-bit integer field
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Bool Boolean field
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// DurationMs Duration field in milliseconds (e.g. duration_ms)
+func DurationMs(key string, d time.Duration) Field {
+	return Field{Key: key, Value: d.Milliseconds()}
+}
+
+// Err Error field under the "error" key; nil errors are kept as null
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error", Value: nil}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// Any Arbitrary field, encoded with encoding/json in JSON mode
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Entry Logger bound to a module and a set of fields
+type Entry struct {
+	logger *Logger
+	module string
+	fields []Field
+}
+
+// WithFields Return a new entry with additional fields
+func (e *Entry) WithFields(fields ...Field) *Entry {
+	merged := make([]Field, 0, len(e.fields)+len(fields))
+	merged = append(merged, e.fields...)
+	merged = append(merged, fields...)
+	return &Entry{logger: e.logger, module: e.module, fields: merged}
+}
+
+// Debug Log debug message with fields
+func (e *Entry) Debug(format string, args ...interface{}) {
+	e.log(DEBUG, format, args...)
+}
+
+// Info Log info message with fields
+func (e *Entry) Info(format string, args ...interface{}) {
+	e.log(INFO, format, args...)
+}
+
+// Warn Log warning message with fields
+func (e *Entry) Warn(format string, args ...interface{}) {
+	e.log(WARN, format, args...)
+}
+
+// Error Log error message with fields
+func (e *Entry) Error(format string, args ...interface{}) {
+	e.log(ERROR, format, args...)
+}
+
+// Log Log message with fields at the given level
+func (e *Entry) Log(level LogLevel, format string, args ...interface{}) {
+	e.log(level, format, args...)
+}
+
+func (e *Entry) log(level LogLevel, format string, args ...interface{}) {
+	e.logger.output(level, e.module, e.fields, DefaultLogCallerDepth+1, format, args...)
+}
+
+// formatTextFields Render fields as " key=value" pairs for text output
+func formatTextFields(fields []Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	for _, f := range fields {
+		sb.WriteByte(' ')
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		s := fmt.Sprint(f.Value)
+		if f.Value == nil {
+			s = "<nil>"
+		}
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			s = strconv.Quote(s)
+		}
+		sb.WriteString(s)
+	}
+	return sb.String()
+}
+
+// appendJSONPair Append `"key":value` to buf, prefixed with a comma unless first
+func appendJSONPair(buf []byte, key string, value interface{}, first bool) []byte {
+	if !first {
+		buf = append(buf, ',')
+	}
+	k, _ := json.Marshal(key)
+	buf = append(buf, k...)
+	buf = append(buf, ':')
+
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return append(buf, v...)
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,12 +22,19 @@ const (
 	ERROR
 )
 
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
 const (
 	DefaultLogCallerDepth = 2
 	DefaultLogFilePerm    = 0644
 	DefaultLogDirPerm     = 0755
 	LogTimeFormat         = "2006-01-02 15:04:05"
 	LogBackupTimeFormat   = "20060102_150405"
+	LogJSONTimeFormat     = time.RFC3339Nano
 )
 
 type LoggerConfig struct {
@@ -35,6 +43,7 @@ type LoggerConfig struct {
 	FilePath   string
 	MaxSize    int64
 	ShowCaller bool
+	Format     string
 }
 
 // Logger Logger structure
@@ -120,6 +129,13 @@ func (l *Logger) SetCaller(enable bool) {
 	l.config.ShowCaller = enable
 }
 
+// SetFormat Set output format: text (default) or json (one object per line)
+func (l *Logger) SetFormat(format string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.config.Format = format
+}
+
 // Debug Log debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
 	l.log(DEBUG, format, args...)
@@ -141,13 +157,16 @@ func (l *Logger) Error(format string, args ...interface{}) {
 }
 
 func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
-	if level < l.config.Level {
+	l.output(level, "", nil, l.caller+1, format, args...)
+}
+
+// output Format and write one log entry. skip is the number of stack frames
+// between output and the caller to report (0 = output itself).
+func (l *Logger) output(level LogLevel, module string, fields []Field, skip int, format string, args ...interface{}) {
+	if level < l.GetLevel() {
 		return
 	}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
 	var message string
 	if len(args) > 0 {
 		message = fmt.Sprintf(format, args...)
@@ -155,37 +174,73 @@ func (l *Logger) log(level LogLevel, format string, args ...interface{}) {
 		message = format
 	}
 
-	l.writeTextLog(level, message)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var caller string
+	if l.config.ShowCaller || l.config.Format == FormatJSON {
+		if _, file, line, ok := runtime.Caller(skip); ok {
+			caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+		}
+	}
+
+	if l.config.Format == FormatJSON {
+		l.writeJSONLog(level, module, caller, message, fields)
+	} else {
+		if !l.config.ShowCaller {
+			caller = ""
+		}
+		l.writeTextLog(level, module, caller, message, fields)
+	}
 
 	if l.file != nil && l.config.MaxSize > 0 {
 		l.rotateLog()
 	}
 }
 
-func (l *Logger) writeTextLog(level LogLevel, message string) {
+func (l *Logger) writeTextLog(level LogLevel, module, caller, message string, fields []Field) {
 	timestamp := time.Now().Format(LogTimeFormat)
 	levelStr := l.levelToString(level)
 
-	var callerInfo string
-	if l.config.ShowCaller {
-		_, file, line, ok := runtime.Caller(l.caller)
-		if ok {
-			callerInfo = fmt.Sprintf(" [%s:%d]", filepath.Base(file), line)
-		}
+	logEntry := fmt.Sprintf("[%s] %s", timestamp, levelStr)
+	if module != "" {
+		logEntry += " [" + module + "]"
 	}
-
-	var logEntry string
-	if callerInfo != "" {
-		logEntry = fmt.Sprintf("[%s] %s%s %s\n",
-			timestamp, levelStr, callerInfo, message)
-	} else {
-		logEntry = fmt.Sprintf("[%s] %s %s\n",
-			timestamp, levelStr, message)
+	if caller != "" {
+		logEntry += " [" + caller + "]"
 	}
+	logEntry += " " + message + formatTextFields(fields) + "\n"
 
 	l.config.Output.Write([]byte(logEntry))
 }
 
+// reservedJSONKeys Keys written by writeJSONLog itself; fields using them get the "fields." prefix
+var reservedJSONKeys = map[string]bool{"ts": true, "level": true, "module": true, "caller": true, "msg": true}
+
+func (l *Logger) writeJSONLog(level LogLevel, module, caller, message string, fields []Field) {
+	buf := make([]byte, 0, 256)
+	buf = append(buf, '{')
+	buf = appendJSONPair(buf, "ts", time.Now().Format(LogJSONTimeFormat), true)
+	buf = appendJSONPair(buf, "level", l.levelToString(level), false)
+	if module != "" {
+		buf = appendJSONPair(buf, "module", module, false)
+	}
+	if caller != "" {
+		buf = appendJSONPair(buf, "caller", caller, false)
+	}
+	buf = appendJSONPair(buf, "msg", message, false)
+	for _, f := range fields {
+		key := f.Key
+		if reservedJSONKeys[key] {
+			key = "fields." + key
+		}
+		buf = appendJSONPair(buf, key, f.Value, false)
+	}
+	buf = append(buf, '}', '\n')
+
+	l.config.Output.Write(buf)
+}
+
 // levelToString Convert log level to string
 func (l *Logger) levelToString(level LogLevel) string {
 	switch level {
@@ -202,6 +257,22 @@ func (l *Logger) levelToString(level LogLevel) string {
 	}
 }
 
+// ParseLevel Parse a level name (debug, info, warn, error), case-insensitive
+func ParseLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
 // rotateLog Rotate log file if needed
 func (l *Logger) rotateLog() {
 	if l.file == nil {
@@ -248,6 +319,11 @@ func (l *Logger) GetLevel() LogLevel {
 	return l.config.Level
 }
 
+// WithFields Return an entry that attaches the given fields to every message
+func (l *Logger) WithFields(fields ...Field) *Entry {
+	return &Entry{logger: l, fields: fields}
+}
+
 func (l *Logger) WithModule(module string) *ModuleLogger {
 	return &ModuleLogger{
 		logger: l,
@@ -280,48 +356,11 @@ func (ml *ModuleLogger) Error(format string, args ...interface{}) {
 	ml.logWithModule(ERROR, format, args...)
 }
 
-func (ml *ModuleLogger) logWithModule(level LogLevel, format string, args ...interface{}) {
-	if level < ml.logger.config.Level {
-		return
-	}
-
-	ml.logger.mu.Lock()
-	defer ml.logger.mu.Unlock()
-
-	var message string
-	if len(args) > 0 {
-		message = fmt.Sprintf(format, args...)
-	} else {
-		message = format
-	}
-
-	ml.writeTextLogWithModule(level, message)
-
-	if ml.logger.file != nil && ml.logger.config.MaxSize > 0 {
-		ml.logger.rotateLog()
-	}
+// WithFields Return an entry of this module that attaches the given fields
+func (ml *ModuleLogger) WithFields(fields ...Field) *Entry {
+	return &Entry{logger: ml.logger, module: ml.module, fields: fields}
 }
 
-func (ml *ModuleLogger) writeTextLogWithModule(level LogLevel, message string) {
-	timestamp := time.Now().Format(LogTimeFormat)
-	levelStr := ml.logger.levelToString(level)
-
-	var callerInfo string
-	if ml.logger.config.ShowCaller {
-		_, file, line, ok := runtime.Caller(DefaultLogCallerDepth)
-		if ok {
-			callerInfo = fmt.Sprintf(" [%s:%d]", filepath.Base(file), line)
-		}
-	}
-
-	var logEntry string
-	if callerInfo != "" {
-		logEntry = fmt.Sprintf("[%s] %s [%s]%s %s\n",
-			timestamp, levelStr, ml.module, callerInfo, message)
-	} else {
-		logEntry = fmt.Sprintf("[%s] %s [%s] %s\n",
-			timestamp, levelStr, ml.module, message)
-	}
-
-	ml.logger.config.Output.Write([]byte(logEntry))
+func (ml *ModuleLogger) logWithModule(level LogLevel, format string, args ...interface{}) {
+	ml.logger.output(level, ml.module, nil, DefaultLogCallerDepth+1, format, args...)
 }
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(DEBUG, &buf)
+	l.SetFormat(FormatJSON)
+
+	l.WithModule("monitor").WithFields(
+		String("monitor_id", "m1"),
+		String("path", "/data/a b.txt"),
+		DurationMs("duration_ms", 1500*time.Millisecond),
+		Int("exit_code", 2),
+		Err(errors.New("boom")),
+	).Error("command failed: %s", "x")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, buf.String())
+	}
+	want := map[string]interface{}{
+		"level":       "ERROR",
+		"module":      "monitor",
+		"msg":         "command failed: x",
+		"monitor_id":  "m1",
+		"path":        "/data/a b.txt",
+		"duration_ms": float64(1500),
+		"exit_code":   float64(2),
+		"error":       "boom",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+	if _, err := time.Parse(LogJSONTimeFormat, entry["ts"].(string)); err != nil {
+		t.Errorf("ts not RFC3339: %v", entry["ts"])
+	}
+	if caller, _ := entry["caller"].(string); !strings.HasPrefix(caller, "logger_test.go:") {
+		t.Errorf("caller = %q, want logger_test.go:<line>", caller)
+	}
+}
+
+func TestJSONFormatReservedFieldKeys(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(INFO, &buf)
+	l.SetFormat(FormatJSON)
+
+	l.WithFields(String("msg", "field"), String("level", "high"), String("ts", "then"), String("path", "/a")).Info("message")
+
+	for _, key := range []string{`"msg":`, `"level":`, `"ts":`} {
+		if n := strings.Count(buf.String(), key); n != 1 {
+			t.Errorf("%s appears %d times in %s", key, n, buf.String())
+		}
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("output is not a JSON object: %v\n%s", err, buf.String())
+	}
+	want := map[string]interface{}{
+		"msg":          "message",
+		"level":        "INFO",
+		"fields.msg":   "field",
+		"fields.level": "high",
+		"fields.ts":    "then",
+		"path":         "/a",
+	}
+	for k, v := range want {
+		if entry[k] != v {
+			t.Errorf("%s = %v, want %v", k, entry[k], v)
+		}
+	}
+}
+
+func TestTextFormatCaller(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(INFO, &buf)
+	l.SetCaller(true)
+
+	l.Info("plain")
+	l.WithFields(String("path", "a b")).Info("with fields")
+	l.Debug("filtered")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "[logger_test.go:") {
+			t.Errorf("missing caller in %q", line)
+		}
+	}
+	if !strings.HasSuffix(lines[1], `with fields path="a b"`) {
+		t.Errorf("unexpected text fields: %q", lines[1])
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounterVec("test_events_total", "Events seen.", "type", "directory")
+	c.Inc("created", "/data")
+	c.Add(2, "created", "/data")
+	c.Inc("deleted", `/we"ird\dir`)
+
+	g := r.NewGaugeVec("test_queue", "Queue length.")
+	g.Set(7)
+
+	r.GaugeFunc("test_slots", "Slots in use.", func() float64 { return 3 })
+
+	h := r.NewHistogramVec("test_duration_seconds", "Durations.", []float64{1, 0.5}, "monitor_id")
+	h.Observe(0.2, "m1")
+	h.Observe(0.7, "m1")
+	h.Observe(5, "m1")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{monitor_id="m1",le="0.5"} 1
+test_duration_seconds_bucket{monitor_id="m1",le="1"} 2
+test_duration_seconds_bucket{monitor_id="m1",le="+Inf"} 3
+test_duration_seconds_sum{monitor_id="m1"} 5.9
+test_duration_seconds_count{monitor_id="m1"} 3
+# HELP test_events_total Events seen.
+# TYPE test_events_total counter
+test_events_total{type="created",directory="/data"} 3
+test_events_total{type="deleted",directory="/we\"ird\\dir"} 1
+# HELP test_queue Queue length.
+# TYPE test_queue gauge
+test_queue 7
+# HELP test_slots Slots in use.
+# TYPE test_slots gauge
+test_slots 3
+`
+	if got := b.String(); got != want {
+		t.Fatalf("unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterIgnoresNegativeDelta(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_total", "Total.")
+	c.Add(-1)
+
+	var b strings.Builder
+	_ = r.WriteText(&b)
+	if strings.Contains(b.String(), "\ntest_total ") {
+		t.Fatalf("negative delta created a series:\n%s", b.String())
+	}
+}
@@ -19,4 +19,8 @@ type FileEvent struct {
 	Size      int64
 	ModTime   time.Time
 	Directory string
+	// Synthetic 为 true 表示事件由启动扫描生成，而非文件系统通知
+	Synthetic bool
+	// Seq 事件在预写日志中的序号，0 表示未写入日志
+	Seq uint64
 }
@@ -6,11 +6,15 @@ type Settings struct {
 	LogMaxSize    int64  `json:"log_max_size,omitempty"`
 	LogMaxBackups int    `json:"log_max_backups,omitempty"`
 	LogShowCaller bool   `json:"log_show_caller,omitempty"`
+	LogFormat     string `json:"log_format,omitempty"`
 
 	MaxConcurrentOperations int `json:"max_concurrent_operations,omitempty"`
 	OperationTimeoutSeconds int `json:"operation_timeout_seconds,omitempty"`
 
 	FileWatcherBufferSize uint32 `json:"file_watcher_buffer_size,omitempty"`
+	PollIntervalMs        int    `json:"poll_interval_ms,omitempty"`
+	// Ignore 全局忽略规则（gitignore 语法），未配置时使用默认规则
+	Ignore []string `json:"ignore,omitempty"`
 
 	EventChannelBufferSize int `json:"event_channel_buffer_size,omitempty"`
 	MinStabilityTimeMs     int `json:"min_stability_time_ms,omitempty"`
@@ -20,8 +24,23 @@ type Settings struct {
 	DirectoryStabilityQuietMs        int `json:"directory_stability_quiet_ms,omitempty"`
 	DirectoryStabilityTimeoutSeconds int `json:"directory_stability_timeout_seconds,omitempty"`
 
-	RetryAttempts     int `json:"retry_attempts,omitempty"`
-	RetryDelaySeconds int `json:"retry_delay_seconds,omitempty"`
+	// RetryAttempts 失败后的重试次数，默认 0（不重试），命令可安全重复执行时再开启
+	RetryAttempts         int    `json:"retry_attempts,omitempty"`
+	RetryDelaySeconds     int    `json:"retry_delay_seconds,omitempty"`
+	RetryBackoff          string `json:"retry_backoff,omitempty"`
+	RetryMaxDelaySeconds  int    `json:"retry_max_delay_seconds,omitempty"`
+	RetryMaxWindowSeconds int    `json:"retry_max_window_seconds,omitempty"`
 
-	HealthCheckIntervalSeconds int `json:"health_check_interval_seconds,omitempty"`
+	HealthCheckIntervalSeconds int  `json:"health_check_interval_seconds,omitempty"`
+	HealthErrorThreshold       int  `json:"health_error_threshold,omitempty"`
+	HealthAutoRewatch          bool `json:"health_auto_rewatch,omitempty"`
+
+	MetricsListen string `json:"metrics_listen,omitempty"`
+
+	DataDir               string `json:"data_dir,omitempty"`
+	HistoryRetentionDays  int    `json:"history_retention_days,omitempty"`
+	HistoryOutputMaxBytes int    `json:"history_output_max_bytes,omitempty"`
+
+	JournalEnabled          bool `json:"journal_enabled,omitempty"`
+	JournalCompactThreshold int  `json:"journal_compact_threshold,omitempty"`
 }
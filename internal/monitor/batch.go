@@ -0,0 +1,229 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// 清单临时文件名前缀
+	ManifestFilePrefix = "dir-monitor-manifest-"
+	// per_file 模式默认并行度
+	DefaultBatchParallelism = 1
+)
+
+// batchFile 清单/标准输入中单个文件的描述
+type batchFile struct {
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	EventType string `json:"event_type"`
+	EventTime string `json:"event_time"`
+}
+
+// batchPayload 以 JSON 形式写入标准输入的批处理内容
+type batchPayload struct {
+	MonitorID string      `json:"monitor_id"`
+	Directory string      `json:"directory"`
+	Files     []batchFile `json:"files"`
+}
+
+func newBatchFiles(events []model.FileEvent) []batchFile {
+	files := make([]batchFile, 0, len(events))
+	for _, event := range events {
+		files = append(files, batchFile{
+			Path:      event.Path,
+			Name:      filepath.Base(event.Path),
+			EventType: string(event.Type),
+			EventTime: event.Timestamp.Format(time.RFC3339),
+		})
+	}
+	return files
+}
+
+func eventPaths(events []model.FileEvent) []string {
+	paths := make([]string, 0, len(events))
+	for _, event := range events {
+		paths = append(paths, event.Path)
+	}
+	return paths
+}
+
+// writeManifest 将文件列表写入临时清单文件，返回清单路径
+func writeManifest(events []model.FileEvent, format string) (string, error) {
+	ext := ".txt"
+	if format == config.ManifestFormatJSON {
+		ext = ".json"
+	}
+
+	f, err := os.CreateTemp("", ManifestFilePrefix+"*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("create manifest failed: %w", err)
+	}
+
+	var data []byte
+	if format == config.ManifestFormatJSON {
+		data, err = json.MarshalIndent(newBatchFiles(events), "", "  ")
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", fmt.Errorf("encode manifest failed: %w", err)
+		}
+	} else {
+		data = []byte(strings.Join(eventPaths(events), "\n") + "\n")
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", fmt.Errorf("write manifest failed: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("close manifest failed: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// presentEvents 去掉执行前已不存在的文件（删除事件除外）
+func (m *Monitor) presentEvents(monitor config.Monitor, events []model.FileEvent) []model.FileEvent {
+	present := make([]model.FileEvent, 0, len(events))
+	for _, event := range events {
+		if event.Type != model.FileDeleted {
+			if _, err := os.Stat(event.Path); err != nil {
+				m.execLogger(monitor, event).Info("[Monitor] 文件已不存在，移出批处理")
+				continue
+			}
+		}
+		present = append(present, event)
+	}
+	return present
+}
+
+// executeBatch 按监控项的批处理模式执行命令
+func (m *Monitor) executeBatch(monitor config.Monitor, events []model.FileEvent, tracker *ackTracker) {
+	events = m.presentEvents(monitor, events)
+	if len(events) == 0 {
+		return
+	}
+
+	if monitor.Batch.Mode == config.BatchModePerFile {
+		m.executePerFile(monitor, events, tracker)
+		return
+	}
+
+	paths := eventPaths(events)
+	log := m.logger.WithFields(
+		logger.String("monitor_id", monitorKey(monitor)),
+		logger.String("batch_mode", monitor.Batch.Mode),
+		logger.Int("file_count", len(paths)),
+	)
+	log.Info("[Monitor] 批处理执行")
+
+	if m.isDuplicate(monitor, strings.Join(paths, "|")) {
+		log.Info("[Monitor] 检测到重复批处理，跳过")
+		return
+	}
+
+	first := events[0]
+	executor := m.newExecutor(monitor, first)
+	executor.SetFileList(paths)
+
+	var manifest string
+	switch monitor.Batch.Mode {
+	case config.BatchModeManifest:
+		path, err := writeManifest(events, monitor.Batch.ManifestFormat)
+		if err != nil {
+			log.WithFields(logger.Err(err)).Error("[Monitor] 创建批处理清单失败")
+			return
+		}
+		manifest = path
+		executor.SetEnvVar("MANIFEST", manifest)
+	case config.BatchModeStdin:
+		data, err := json.Marshal(batchPayload{
+			MonitorID: monitor.ID,
+			Directory: monitor.Directory,
+			Files:     newBatchFiles(events),
+		})
+		if err != nil {
+			log.WithFields(logger.Err(err)).Error("[Monitor] 编码批处理输入失败")
+			return
+		}
+		executor.SetStdin(data)
+	}
+
+	policy := resolveRetryPolicy(m.currentConfig().Settings, monitor)
+
+	m.wg.Add(1)
+	tracker.add()
+	go func() {
+		defer m.wg.Done()
+		if manifest != "" {
+			defer os.Remove(manifest)
+		}
+		tracker.done(m.runWithRetry(monitor, executor, &first, policy))
+	}()
+}
+
+// executePerFile 每个文件单独执行一次命令，并行度受 batch.parallelism 限制
+func (m *Monitor) executePerFile(monitor config.Monitor, events []model.FileEvent, tracker *ackTracker) {
+	parallelism := monitor.Batch.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultBatchParallelism
+	}
+
+	m.logger.WithFields(
+		logger.String("monitor_id", monitorKey(monitor)),
+		logger.Int("file_count", len(events)),
+		logger.Int("parallelism", parallelism),
+	).Info("[Monitor] 逐文件执行")
+
+	policy := resolveRetryPolicy(m.currentConfig().Settings, monitor)
+
+	m.wg.Add(1)
+	tracker.add()
+	go func() {
+		defer m.wg.Done()
+
+		slots := make(chan struct{}, parallelism)
+		var batchWg sync.WaitGroup
+		var cancelled int32
+		defer func() {
+			batchWg.Wait()
+			tracker.done(atomic.LoadInt32(&cancelled) == 0)
+		}()
+
+		for _, event := range events {
+			if m.isDuplicate(monitor, event.Path) {
+				m.execLogger(monitor, event).Info("[Monitor] 检测到重复执行，跳过")
+				continue
+			}
+
+			select {
+			case slots <- struct{}{}:
+			case <-m.opCtx.Done():
+				atomic.StoreInt32(&cancelled, 1)
+				return
+			}
+
+			executor := m.newExecutor(monitor, event)
+			batchWg.Add(1)
+			go func(event model.FileEvent) {
+				defer batchWg.Done()
+				defer func() { <-slots }()
+				if !m.runWithRetry(monitor, executor, &event, policy) {
+					atomic.StoreInt32(&cancelled, 1)
+				}
+			}(event)
+		}
+	}()
+}
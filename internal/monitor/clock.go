@@ -0,0 +1,24 @@
+package monitor
+
+import (
+	"time"
+)
+
+// Clock 时间来源，测试中可替换为可控时钟
+type Clock interface {
+	Now() time.Time
+	// AfterFunc 在 d 之后于独立 goroutine 中调用 f
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer 由 Clock.AfterFunc 返回的定时器
+type Timer interface {
+	Stop() bool
+}
+
+// SystemClock 使用系统时间的 Clock
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time { return time.Now() }
+
+func (SystemClock) AfterFunc(d time.Duration, f func()) Timer { return time.AfterFunc(d, f) }
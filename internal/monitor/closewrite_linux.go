@@ -0,0 +1,276 @@
+//go:build linux
+
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"dir-monitor-go/internal/logger"
+)
+
+const closeWriteMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_ONLYDIR
+
+// closeWriteTracker records which files were created or modified and not yet closed
+// after writing, using a dedicated inotify instance (fsnotify does not expose IN_CLOSE_WRITE).
+type closeWriteTracker struct {
+	logger *logger.Logger
+	file   *os.File
+	fd     int
+
+	mu      sync.Mutex
+	watches map[int]string  // watch descriptor -> directory
+	dirs    map[string]int  // directory -> watch descriptor
+	bases   map[string]int  // base directory -> depth
+	dirty   map[string]bool // paths written since their last IN_CLOSE_WRITE
+
+	// overflowAt is when the kernel queue last overflowed (IN_Q_OVERFLOW); events were lost,
+	// so files without an event since then have an unknown state. seen holds the paths
+	// that had an event after the overflow.
+	overflowAt time.Time
+	seen       map[string]bool
+
+	wg sync.WaitGroup
+}
+
+// newCloseWriteTracker creates the inotify instance and starts reading its events
+func newCloseWriteTracker(log *logger.Logger) (*closeWriteTracker, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1 failed: %v", err)
+	}
+	t := &closeWriteTracker{
+		logger: log,
+		// non-blocking descriptors are registered with the runtime poller, so Close unblocks Read
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		watches: make(map[int]string),
+		dirs:    make(map[string]int),
+		bases:   make(map[string]int),
+		dirty:   make(map[string]bool),
+	}
+	t.wg.Add(1)
+	go t.readEvents()
+	return t, nil
+}
+
+// watch registers base and its subdirectories down to depth
+func (t *closeWriteTracker) watch(base string, depth int) error {
+	t.mu.Lock()
+	t.bases[base] = depth
+	t.mu.Unlock()
+	return t.addTree(base, base, depth)
+}
+
+// unwatch removes base; directories still covered by another base keep their watches
+func (t *closeWriteTracker) unwatch(base string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.bases, base)
+	for dir, wd := range t.dirs {
+		if dir != base && !isSubPath(dir, base) {
+			continue
+		}
+		if _, _, ok := t.coveringBaseLocked(dir); ok {
+			continue
+		}
+		_, _ = syscall.InotifyRmWatch(t.fd, uint32(wd))
+		delete(t.dirs, dir)
+		delete(t.watches, wd)
+	}
+	for path := range t.dirty {
+		if _, _, ok := t.coveringBaseLocked(filepath.Dir(path)); !ok {
+			delete(t.dirty, path)
+		}
+	}
+	for path := range t.seen {
+		if _, _, ok := t.coveringBaseLocked(filepath.Dir(path)); !ok {
+			delete(t.seen, path)
+		}
+	}
+}
+
+// watchedBases returns the registered base directories and their depths
+func (t *closeWriteTracker) watchedBases() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	bases := make(map[string]int, len(t.bases))
+	for base, depth := range t.bases {
+		bases[base] = depth
+	}
+	return bases
+}
+
+// ready reports whether the file was closed after its last write. Files not written
+// since the watch was established are assumed complete. known is false when events for
+// the file may have been lost in a queue overflow; the caller must check it another way.
+func (t *closeWriteTracker) ready(path string) (ready, known bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dirty[path] {
+		return false, true
+	}
+	return true, t.overflowAt.IsZero() || t.seen[path]
+}
+
+// close stops reading events and releases the inotify instance
+func (t *closeWriteTracker) close() {
+	_ = t.file.Close()
+	t.wg.Wait()
+}
+
+// coveringBaseLocked returns a base directory whose depth covers dir; the caller holds mu
+func (t *closeWriteTracker) coveringBaseLocked(dir string) (string, int, bool) {
+	for base, depth := range t.bases {
+		if withinDepth(base, dir, depth) {
+			return base, depth, true
+		}
+	}
+	return "", 0, false
+}
+
+// addTree adds watches for root and its subdirectories that lie within depth of base
+func (t *closeWriteTracker) addTree(base, root string, depth int) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			return nil
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if !withinDepth(base, p, depth) {
+			return filepath.SkipDir
+		}
+		return t.addWatch(p)
+	})
+}
+
+func (t *closeWriteTracker) addWatch(dir string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.dirs[dir]; ok {
+		return nil
+	}
+	wd, err := syscall.InotifyAddWatch(t.fd, dir, closeWriteMask)
+	if err != nil {
+		return fmt.Errorf("inotify_add_watch %s failed: %v", dir, err)
+	}
+	t.watches[wd] = dir
+	t.dirs[dir] = wd
+	return nil
+}
+
+func (t *closeWriteTracker) readEvents() {
+	defer t.wg.Done()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := t.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				t.logger.WithFields(logger.Err(err)).Warn("[Monitor] 读取 close_write 事件失败，停止跟踪")
+			}
+			return
+		}
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(raw.Len)
+			if nameEnd > n {
+				break
+			}
+			name := strings.TrimRight(string(buf[nameStart:nameEnd]), "\x00")
+			t.handleEvent(int(raw.Wd), raw.Mask, name)
+			offset = nameEnd
+		}
+	}
+}
+
+func (t *closeWriteTracker) handleEvent(wd int, mask uint32, name string) {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		t.overflow()
+		return
+	}
+
+	t.mu.Lock()
+	dir, ok := t.watches[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(t.watches, wd)
+		if ok && t.dirs[dir] == wd {
+			delete(t.dirs, dir)
+		}
+	}
+	t.mu.Unlock()
+	if !ok || name == "" {
+		return
+	}
+	path := filepath.Join(dir, name)
+
+	switch {
+	case mask&syscall.IN_ISDIR != 0 && mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+		t.mu.Lock()
+		base, depth, covered := t.coveringBaseLocked(path)
+		t.mu.Unlock()
+		if covered {
+			if err := t.addTree(base, path, depth); err != nil {
+				t.logger.WithFields(logger.String("dir", path), logger.Err(err)).Warn("[Monitor] close_write 跟踪新目录失败")
+			}
+		}
+	case mask&(syscall.IN_CREATE|syscall.IN_MODIFY) != 0:
+		t.mu.Lock()
+		t.dirty[path] = true
+		t.markSeenLocked(path)
+		t.mu.Unlock()
+	case mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+		t.mu.Lock()
+		delete(t.dirty, path)
+		delete(t.seen, path)
+		t.mu.Unlock()
+	default:
+		// IN_CLOSE_WRITE, or a file renamed into place (written under its temporary name)
+		t.mu.Lock()
+		delete(t.dirty, path)
+		t.markSeenLocked(path)
+		t.mu.Unlock()
+	}
+}
+
+// markSeenLocked records an event received after the last overflow; the caller holds mu
+func (t *closeWriteTracker) markSeenLocked(path string) {
+	if !t.overflowAt.IsZero() {
+		t.seen[path] = true
+	}
+}
+
+// overflow handles IN_Q_OVERFLOW. The IN_CLOSE_WRITE for a dirty file may have been lost,
+// which would keep it waiting forever, so every file becomes unknown until its next event.
+// Directories created during the overflow are picked up by walking the bases again.
+func (t *closeWriteTracker) overflow() {
+	t.mu.Lock()
+	t.overflowAt = time.Now()
+	t.dirty = make(map[string]bool)
+	t.seen = make(map[string]bool)
+	bases := make(map[string]int, len(t.bases))
+	for base, depth := range t.bases {
+		bases[base] = depth
+	}
+	t.mu.Unlock()
+
+	t.logger.Warn("[Monitor] close_write 事件队列溢出，未确认的文件改为检查大小是否稳定")
+	for base, depth := range bases {
+		if err := t.addTree(base, base, depth); err != nil {
+			t.logger.WithFields(logger.String("dir", base), logger.Err(err)).Warn("[Monitor] close_write 重新注册目录失败")
+		}
+	}
+}
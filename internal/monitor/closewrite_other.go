@@ -0,0 +1,27 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"errors"
+
+	"dir-monitor-go/internal/logger"
+)
+
+// closeWriteTracker is unavailable without inotify; the close_write strategy falls back
+// to size_stable because newCloseWriteTracker always fails.
+type closeWriteTracker struct{}
+
+func newCloseWriteTracker(log *logger.Logger) (*closeWriteTracker, error) {
+	return nil, errors.New("close_write tracking requires inotify (linux only)")
+}
+
+func (t *closeWriteTracker) watch(base string, depth int) error { return nil }
+
+func (t *closeWriteTracker) unwatch(base string) {}
+
+func (t *closeWriteTracker) watchedBases() map[string]int { return nil }
+
+func (t *closeWriteTracker) ready(path string) (ready, known bool) { return true, true }
+
+func (t *closeWriteTracker) close() {}
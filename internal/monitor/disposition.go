@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+)
+
+const (
+	// 文件处置产生的事件在此时间内被忽略，避免回流到事件处理
+	SelfEventIgnoreWindow = 10 * time.Second
+	// 处置目标文件名中的时间戳格式
+	DispositionTimeFormat = "20060102-150405"
+	// 隔离文件旁的原因说明文件后缀
+	QuarantineReasonSuffix = ".reason"
+
+	dispositionDirPerm = 0755
+)
+
+// ignoreSelfEvents 记录即将由本进程产生变化的路径，期间收到的这些路径的事件将被丢弃
+func (m *Monitor) ignoreSelfEvents(paths ...string) {
+	until := time.Now().Add(SelfEventIgnoreWindow)
+	m.selfMu.Lock()
+	for _, p := range paths {
+		m.selfEvents[filepath.Clean(p)] = until
+	}
+	m.selfMu.Unlock()
+}
+
+// isSelfEvent 判断事件路径是否由本进程的文件处置产生
+func (m *Monitor) isSelfEvent(path string) bool {
+	m.selfMu.Lock()
+	defer m.selfMu.Unlock()
+	until, ok := m.selfEvents[filepath.Clean(path)]
+	if !ok {
+		return false
+	}
+	if time.Now().After(until) {
+		delete(m.selfEvents, filepath.Clean(path))
+		return false
+	}
+	return true
+}
+
+// cleanupSelfEvents 清理过期的忽略记录
+func (m *Monitor) cleanupSelfEvents(now time.Time) {
+	m.selfMu.Lock()
+	for p, until := range m.selfEvents {
+		if now.After(until) {
+			delete(m.selfEvents, p)
+		}
+	}
+	m.selfMu.Unlock()
+}
+
+// applyDisposition 按执行结果对触发文件执行 on_success/on_failure 动作
+func (m *Monitor) applyDisposition(monitor config.Monitor, paths []string, execErr error) {
+	dc := monitor.OnSuccess
+	outcome := "on_success"
+	if execErr != nil {
+		dc = monitor.OnFailure
+		outcome = "on_failure"
+	}
+	// 触发文件在执行前已消失时无需处置
+	if dc == nil || errors.Is(execErr, ErrFileNotFound) {
+		return
+	}
+
+	now := time.Now()
+	for _, path := range paths {
+		log := m.logger.WithFields(
+			logger.String("monitor_id", monitorKey(monitor)),
+			logger.String("path", path),
+			logger.String("action", dc.Action),
+		)
+		dest, err := m.disposeFile(monitor, dc, path, execErr, now)
+		if err != nil {
+			log.WithFields(logger.Err(err)).Error("[Monitor] 文件处置失败(%s)", outcome)
+			continue
+		}
+		if dest != "" {
+			log.WithFields(logger.String("dest", dest)).Info("[Monitor] 文件处置完成(%s)", outcome)
+		} else {
+			log.Info("[Monitor] 文件处置完成(%s)", outcome)
+		}
+	}
+}
+
+// disposeFile 对单个文件执行处置动作，返回目标路径（delete 时为空）
+func (m *Monitor) disposeFile(monitor config.Monitor, dc *config.DispositionConfig, path string, execErr error, now time.Time) (string, error) {
+	if _, err := os.Lstat(path); err != nil {
+		return "", fmt.Errorf("file no longer exists: %w", err)
+	}
+
+	switch dc.Action {
+	case config.DispositionDelete:
+		m.ignoreSelfEvents(path)
+		return "", os.Remove(path)
+
+	case config.DispositionRename:
+		dest := timestampedPath(filepath.Dir(path), filepath.Base(path), now)
+		m.ignoreSelfEvents(path, dest)
+		return dest, moveFile(path, dest)
+
+	case config.DispositionMove, config.DispositionCopy, config.DispositionQuarantine:
+		target := dc.TargetDir(monitor.Directory)
+		if err := os.MkdirAll(target, dispositionDirPerm); err != nil {
+			return "", fmt.Errorf("failed to create target directory: %w", err)
+		}
+
+		dest := filepath.Join(target, filepath.Base(path))
+		if dc.TimestampSuffix || fileExists(dest) {
+			dest = timestampedPath(target, filepath.Base(path), now)
+		}
+
+		if dc.Action == config.DispositionCopy {
+			m.ignoreSelfEvents(dest)
+			return dest, copyFileAtomic(path, dest)
+		}
+
+		m.ignoreSelfEvents(path, dest, dest+QuarantineReasonSuffix)
+		if err := moveFile(path, dest); err != nil {
+			return "", err
+		}
+		if dc.Action == config.DispositionQuarantine {
+			if err := writeQuarantineReason(dest, monitor, execErr, now); err != nil {
+				return dest, fmt.Errorf("file quarantined but reason file failed: %w", err)
+			}
+		}
+		return dest, nil
+	}
+	return "", fmt.Errorf("unknown action: %s", dc.Action)
+}
+
+// timestampedPath 在扩展名前插入时间戳：report.csv -> report.20060102-150405.csv，
+// 仍冲突时追加序号
+func timestampedPath(dir, name string, now time.Time) string {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	base := stem + "." + now.Format(DispositionTimeFormat)
+	dest := filepath.Join(dir, base+ext)
+	for i := 1; fileExists(dest); i++ {
+		dest = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+	}
+	return dest
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// moveFile 同一文件系统内原子重命名；跨设备时复制到目标（fsync 后原子替换）再删除源文件
+func moveFile(src, dest string) error {
+	err := os.Rename(src, dest)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	if err := copyFileAtomic(src, dest); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFileAtomic 复制到目标目录下的隐藏临时文件，fsync 后重命名为目标文件，
+// 保证目标路径只会出现完整文件
+func copyFileAtomic(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dest), "."+filepath.Base(dest)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	_ = os.Chtimes(tmpName, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmpName, dest); err != nil {
+		return err
+	}
+	committed = true
+	syncDir(filepath.Dir(dest))
+	return nil
+}
+
+// syncDir 尽力将目录项落盘（部分平台不支持对目录 fsync）
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+}
+
+// writeQuarantineReason 在隔离文件旁写入失败原因
+func writeQuarantineReason(dest string, monitor config.Monitor, execErr error, now time.Time) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "time: %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "monitor: %s\n", monitorKey(monitor))
+	fmt.Fprintf(&sb, "command: %s\n", monitor.CommandLine())
+	if code, ok := exitCode(execErr); ok {
+		fmt.Fprintf(&sb, "exit_code: %d\n", code)
+	}
+	if execErr != nil {
+		fmt.Fprintf(&sb, "error: %v\n", execErr)
+	}
+	return os.WriteFile(dest+QuarantineReasonSuffix, []byte(sb.String()), 0644)
+}
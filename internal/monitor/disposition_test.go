@@ -0,0 +1,60 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimestampedPath(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 5, 6, 7, 8, 9, 0, time.Local)
+
+	first := timestampedPath(dir, "report.csv", now)
+	if want := filepath.Join(dir, "report.20260506-070809.csv"); first != want {
+		t.Fatalf("got %s, want %s", first, want)
+	}
+	if err := os.WriteFile(first, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := timestampedPath(dir, "report.csv", now), filepath.Join(dir, "report.20260506-070809-1.csv"); got != want {
+		t.Errorf("collision: got %s, want %s", got, want)
+	}
+}
+
+func TestCopyFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.txt")
+	dest := filepath.Join(dir, "out", "dest.txt")
+	if err := os.WriteFile(src, []byte("payload"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFileAtomic(src, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil || string(data) != "payload" {
+		t.Fatalf("dest content %q, err %v", data, err)
+	}
+	info, _ := os.Stat(dest)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	// 不留下临时文件
+	entries, _ := os.ReadDir(filepath.Dir(dest))
+	if len(entries) != 1 {
+		t.Errorf("unexpected files in target dir: %v", entries)
+	}
+
+	if err := moveFile(src, filepath.Join(dir, "moved.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(src) {
+		t.Error("source still exists after move")
+	}
+}
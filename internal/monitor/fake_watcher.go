@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"dir-monitor-go/internal/model"
+)
+
+// FakeWatcher in-memory Watcher for tests: synthetic events are pushed with Emit
+type FakeWatcher struct {
+	mu      sync.Mutex
+	watched map[string]int // dir -> depth
+	events  chan model.FileEvent
+	closed  bool
+	// WatchErr, if set, is returned by Watch
+	WatchErr error
+	// WatchErrs, if set, maps directories to the error Watch returns for them
+	WatchErrs map[string]error
+}
+
+var _ Watcher = (*FakeWatcher)(nil)
+
+// NewFakeWatcher Create a fake watcher with the default event buffer
+func NewFakeWatcher() *FakeWatcher {
+	return &FakeWatcher{
+		watched: make(map[string]int),
+		events:  make(chan model.FileEvent, DefaultEventChannelBuffer),
+	}
+}
+
+// Watch records dir as watched with the given depth
+func (fw *FakeWatcher) Watch(dir string, depth int) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.WatchErr != nil {
+		return fw.WatchErr
+	}
+	if err := fw.WatchErrs[dir]; err != nil {
+		return err
+	}
+	fw.watched[dir] = depth
+	return nil
+}
+
+// Unwatch forgets dir
+func (fw *FakeWatcher) Unwatch(dir string) error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	delete(fw.watched, dir)
+	return nil
+}
+
+// IsWatching reports whether dir is watched
+func (fw *FakeWatcher) IsWatching(dir string) bool {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	_, ok := fw.watched[dir]
+	return ok
+}
+
+// Depth returns the depth dir was watched with
+func (fw *FakeWatcher) Depth(dir string) (int, bool) {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	depth, ok := fw.watched[dir]
+	return depth, ok
+}
+
+// Rewatch marks dir as watched again, keeping its depth
+func (fw *FakeWatcher) Rewatch(dir string) error {
+	depth, _ := fw.Depth(dir)
+	return fw.Watch(dir, depth)
+}
+
+// WatchCount returns the number of watched directories
+func (fw *FakeWatcher) WatchCount() int {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	return len(fw.watched)
+}
+
+// ErrorCount always returns 0
+func (fw *FakeWatcher) ErrorCount() uint64 {
+	return 0
+}
+
+// Watched returns the sorted list of watched directories
+func (fw *FakeWatcher) Watched() []string {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	dirs := make([]string, 0, len(fw.watched))
+	for dir := range fw.watched {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// Emit pushes event to the event channel, filling Directory and Timestamp when empty.
+// It blocks while the channel is full.
+func (fw *FakeWatcher) Emit(event model.FileEvent) error {
+	if event.Directory == "" {
+		event.Directory = filepath.Dir(event.Path)
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if fw.closed {
+		return fmt.Errorf("fake watcher is closed")
+	}
+	fw.events <- event
+	return nil
+}
+
+// Events returns the event channel
+func (fw *FakeWatcher) Events() <-chan model.FileEvent {
+	return fw.events
+}
+
+// Close closes the event channel; it is safe to call more than once
+func (fw *FakeWatcher) Close() error {
+	fw.mu.Lock()
+	defer fw.mu.Unlock()
+	if !fw.closed {
+		fw.closed = true
+		close(fw.events)
+	}
+	return nil
+}
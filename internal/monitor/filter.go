@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+)
+
+// 文件被属性过滤拒绝的原因（指标 reason 标签值）
+const (
+	rejectStat     = "stat_failed"
+	rejectTooSmall = "too_small"
+	rejectTooLarge = "too_large"
+	rejectTooNew   = "too_new"
+	rejectTooOld   = "too_old"
+	rejectOwner    = "owner"
+	rejectGroup    = "group"
+	rejectMode     = "mode"
+	rejectMIMEType = "mime_type"
+)
+
+// sniffHeaderSize 识别内容类型时读取的文件开头字节数（http.DetectContentType 最多使用 512 字节）
+const sniffHeaderSize = 512
+
+// filterReject 判断文件是否满足监控项的属性过滤条件，不满足时返回原因与说明
+func filterReject(filters *config.FilterConfig, filePath string, now time.Time) (reason, detail string) {
+	if filters == nil {
+		return "", ""
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return rejectStat, err.Error()
+	}
+
+	size := info.Size()
+	switch {
+	case filters.MinSize > 0 && size < filters.MinSize:
+		return rejectTooSmall, fmt.Sprintf("size %d < min_size %d", size, filters.MinSize)
+	case filters.MaxSize > 0 && size > filters.MaxSize:
+		return rejectTooLarge, fmt.Sprintf("size %d > max_size %d", size, filters.MaxSize)
+	}
+
+	age := now.Sub(info.ModTime())
+	switch {
+	case filters.MinAgeSeconds > 0 && age < time.Duration(filters.MinAgeSeconds)*time.Second:
+		return rejectTooNew, fmt.Sprintf("age %v < min_age_seconds %d", age.Truncate(time.Second), filters.MinAgeSeconds)
+	case filters.MaxAgeSeconds > 0 && age > time.Duration(filters.MaxAgeSeconds)*time.Second:
+		return rejectTooOld, fmt.Sprintf("age %v > max_age_seconds %d", age.Truncate(time.Second), filters.MaxAgeSeconds)
+	}
+
+	if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+		if filters.UID != nil && int(sys.Uid) != *filters.UID {
+			return rejectOwner, fmt.Sprintf("uid %d != %d", sys.Uid, *filters.UID)
+		}
+		if filters.GID != nil && int(sys.Gid) != *filters.GID {
+			return rejectGroup, fmt.Sprintf("gid %d != %d", sys.Gid, *filters.GID)
+		}
+	}
+
+	// Validate 已检查权限位格式
+	required, forbidden, _ := filters.Modes()
+	perm := info.Mode().Perm()
+	if perm&required != required {
+		return rejectMode, fmt.Sprintf("mode %04o lacks %04o", perm, required)
+	}
+	if perm&forbidden != 0 {
+		return rejectMode, fmt.Sprintf("mode %04o has forbidden %04o", perm, perm&forbidden)
+	}
+
+	if len(filters.MIMETypes) > 0 {
+		contentType, err := detectContentType(filePath)
+		if err != nil {
+			return rejectStat, err.Error()
+		}
+		if !matchesMIMEType(contentType, filters.MIMETypes) {
+			return rejectMIMEType, fmt.Sprintf("content type %s not in %v", contentType, filters.MIMETypes)
+		}
+	}
+	return "", ""
+}
+
+// detectContentType 按文件开头的内容识别媒体类型（不含参数），使用 http.DetectContentType 的魔数表
+func detectContentType(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, sniffHeaderSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	contentType := http.DetectContentType(buf[:n])
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType, nil
+	}
+	return contentType, nil
+}
+
+// matchesMIMEType 判断媒体类型是否匹配允许的类型（支持 text/* 形式，不区分大小写）
+func matchesMIMEType(contentType string, allowed []string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(strings.ToLower(pattern), contentType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// minAgeRemaining 返回文件距满足 min_age_seconds 还需等待的时间，已满足或无法判断时返回 0
+func minAgeRemaining(filters *config.FilterConfig, filePath string, now time.Time) time.Duration {
+	if filters == nil || filters.MinAgeSeconds <= 0 {
+		return 0
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0
+	}
+	remaining := time.Duration(filters.MinAgeSeconds)*time.Second - now.Sub(info.ModTime())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// rejectedByFilters 检查监控项的属性过滤条件，拒绝时记录日志与指标
+func (m *Monitor) rejectedByFilters(monitor config.Monitor, filePath string, now time.Time) bool {
+	rejected, _ := m.filterFile(monitor, filePath, now, false)
+	return rejected
+}
+
+// filterFile 检查监控项的属性过滤条件，拒绝时记录日志与指标。
+// deferYoung 为 true 时尚未达到 min_age_seconds 的文件不拒绝，返回还需等待的时间
+func (m *Monitor) filterFile(monitor config.Monitor, filePath string, now time.Time, deferYoung bool) (rejected bool, wait time.Duration) {
+	reason, detail := filterReject(monitor.Filters, filePath, now)
+	if reason == "" {
+		return false, 0
+	}
+	if reason == rejectTooNew && deferYoung {
+		if wait := minAgeRemaining(monitor.Filters, filePath, now); wait > 0 {
+			return false, wait
+		}
+	}
+	metricFilesRejected.Inc(monitorKey(monitor), reason)
+	m.logger.WithFields(
+		logger.String("monitor_id", monitorKey(monitor)),
+		logger.String("path", filePath),
+		logger.String("reason", reason),
+	).Warn("[Monitor] 文件不满足属性过滤条件，跳过: %s", detail)
+	return true, 0
+}
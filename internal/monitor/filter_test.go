@@ -0,0 +1,63 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+)
+
+func TestFilterReject(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"empty.csv": nil,
+		"data.csv":  []byte("a,b,c\n1,2,3\n"),
+		"dump.gz":   {0x1f, 0x8b, 0x08, 0, 0, 0, 0, 0},
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+	modTime := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	for name := range files {
+		if err := os.Chtimes(filepath.Join(dir, name), modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := modTime.Add(10 * time.Minute)
+	uid, other := os.Getuid(), os.Getuid()+1
+
+	tests := []struct {
+		name    string
+		filters *config.FilterConfig
+		file    string
+		want    string
+	}{
+		{"no filters", nil, "empty.csv", ""},
+		{"zero byte", &config.FilterConfig{MinSize: 1}, "empty.csv", rejectTooSmall},
+		{"large enough", &config.FilterConfig{MinSize: 1}, "data.csv", ""},
+		{"too large", &config.FilterConfig{MaxSize: 4}, "data.csv", rejectTooLarge},
+		{"too new", &config.FilterConfig{MinAgeSeconds: 3600}, "data.csv", rejectTooNew},
+		{"too old", &config.FilterConfig{MaxAgeSeconds: 60}, "data.csv", rejectTooOld},
+		{"age in range", &config.FilterConfig{MinAgeSeconds: 60, MaxAgeSeconds: 3600}, "data.csv", ""},
+		{"owner", &config.FilterConfig{UID: &uid}, "data.csv", ""},
+		{"other owner", &config.FilterConfig{UID: &other}, "data.csv", rejectOwner},
+		{"mode required", &config.FilterConfig{ModeRequired: "0040"}, "data.csv", ""},
+		{"mode missing", &config.FilterConfig{ModeRequired: "0004"}, "data.csv", rejectMode},
+		{"mode forbidden", &config.FilterConfig{ModeForbidden: "0040"}, "data.csv", rejectMode},
+		{"mime text", &config.FilterConfig{MIMETypes: []string{"text/*"}}, "data.csv", ""},
+		{"mime gzip rejected", &config.FilterConfig{MIMETypes: []string{"text/plain"}}, "dump.gz", rejectMIMEType},
+		{"mime gzip allowed", &config.FilterConfig{MIMETypes: []string{"application/x-gzip"}}, "dump.gz", ""},
+		{"missing file", &config.FilterConfig{MinSize: 1}, "missing.csv", rejectStat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, detail := filterReject(tt.filters, filepath.Join(dir, tt.file), now); got != tt.want {
+				t.Errorf("reason = %q (%s), want %q", got, detail, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package monitor
+
+// exclusiveOpen 没有 flock 的平台无法判断写入方是否持有锁，视为已写入完成
+func exclusiveOpen(path string) bool {
+	return true
+}
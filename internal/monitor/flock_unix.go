@@ -0,0 +1,24 @@
+//go:build unix && !linux
+
+package monitor
+
+import (
+	"os"
+	"syscall"
+)
+
+// exclusiveOpen 尝试以非阻塞方式取得文件的排他 flock，写入方持有锁时返回 false。
+// flock 是建议锁，只能发现同样加锁的写入方；SFTP、scp、rsync 等不加锁的写入方
+// 在写入期间也会被判断为已完成
+func exclusiveOpen(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		return false
+	}
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	return true
+}
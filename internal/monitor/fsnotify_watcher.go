@@ -10,6 +10,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"dir-monitor-go/internal/config"
 	"dir-monitor-go/internal/logger"
 	"dir-monitor-go/internal/model"
 
@@ -29,11 +30,6 @@ const (
 	DefaultEventChannelBuffer = 100
 )
 
-type handlerEntry struct {
-	baseDir string
-	fn      func(model.FileEvent)
-}
-
 // FsnotifyWatcher File system monitor based on native fsnotify library (event-driven implementation)
 type FsnotifyWatcher struct {
 	logger      *logger.Logger
@@ -44,13 +40,20 @@ type FsnotifyWatcher struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
-	handlers    []handlerEntry
+	baseDirs    map[string]int // directories passed to Watch -> depth
 
-	// recent REMOVE cache for pairing with CREATE -> renamed
+	// recent REMOVE/RENAME cache for pairing with CREATE -> renamed
 	movePairs map[string]renamePair // key: directory path
 
 	// stop flag to prevent any further event logging/dispatch after Stop
 	stopping int32
+
+	// ensures the event loop goroutine is started only once
+	startOnce sync.Once
+	closeOnce sync.Once
+
+	// total number of errors received from fsnotify
+	errorCount uint64
 }
 
 // NewFsnotifyWatcher Create a new monitor based on native fsnotify (event-driven implementation)
@@ -76,46 +79,114 @@ func NewFsnotifyWatcher(logger *logger.Logger) *FsnotifyWatcher {
 		events:      make(chan model.FileEvent, eventChannelBuffer),
 		ctx:         ctx,
 		cancel:      cancel,
-		handlers:    make([]handlerEntry, 0),
+		baseDirs:    make(map[string]int),
 		movePairs:   make(map[string]renamePair),
 	}
 }
 
-// Watch starts monitoring specified directory
-func (fw *FsnotifyWatcher) Watch(baseDir string, handler func(model.FileEvent)) error {
+var _ Watcher = (*FsnotifyWatcher)(nil)
+var _ WatchInspector = (*FsnotifyWatcher)(nil)
+
+// Watch starts monitoring specified directory and its subdirectories down to depth;
+// events are delivered through Events
+func (fw *FsnotifyWatcher) Watch(baseDir string, depth int) error {
 	fw.mu.Lock()
-	fw.handlers = append(fw.handlers, handlerEntry{baseDir: baseDir, fn: handler})
+	fw.baseDirs[baseDir] = depth
 	fw.mu.Unlock()
 
-	// Set up directory watch (non-recursive)
-	if err := fw.setupWatch(baseDir); err != nil {
+	if err := fw.setupWatch(baseDir, depth); err != nil {
 		return fmt.Errorf("failed to setup watch for %s: %w", baseDir, err)
 	}
 
-	fw.wg.Add(1)
-	go fw.processEvents()
+	fw.startOnce.Do(func() {
+		fw.wg.Add(1)
+		go fw.processEvents()
+	})
 
 	return nil
 }
 
-// Stop stops monitoring
-func (fw *FsnotifyWatcher) Stop() error {
-	// mark stopping first to avoid race logging in handlers
-	atomic.StoreInt32(&fw.stopping, 1)
-	fw.cancel()
-	if fw.fsWatcher != nil {
-		_ = fw.fsWatcher.Close()
+// Unwatch stops monitoring specified directory
+func (fw *FsnotifyWatcher) Unwatch(baseDir string) error {
+	fw.mu.Lock()
+	delete(fw.baseDirs, baseDir)
+	remaining := make(map[string]int, len(fw.baseDirs))
+	for dir, depth := range fw.baseDirs {
+		remaining[dir] = depth
+	}
+	fw.mu.Unlock()
+
+	// still covered by a parent base directory: keep the watches
+	for dir := range remaining {
+		if dir == baseDir || isSubPath(baseDir, dir) {
+			return nil
+		}
+	}
+
+	fw.removeWatchRecursive(baseDir)
+
+	// re-establish watches of nested base directories removed above
+	for dir, depth := range remaining {
+		if isSubPath(dir, baseDir) {
+			if err := fw.setupWatch(dir, depth); err != nil {
+				fw.logger.Warn("[FsnotifyWatcher] Failed to restore watch for %s: %v", dir, err)
+			}
+		}
 	}
-	fw.wg.Wait()
-	close(fw.events)
 	return nil
 }
 
+// WatchCount returns the number of directories registered with fsnotify
+func (fw *FsnotifyWatcher) WatchCount() int {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	return len(fw.watchedDirs)
+}
+
+// IsWatching reports whether dir is currently registered with fsnotify
+func (fw *FsnotifyWatcher) IsWatching(dir string) bool {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
+	return fw.watchedDirs[dir]
+}
 
+// Rewatch re-registers dir (and its subdirectories) with fsnotify
+func (fw *FsnotifyWatcher) Rewatch(dir string) error {
+	fw.mu.RLock()
+	depth := fw.baseDirs[dir]
+	fw.mu.RUnlock()
+	return fw.setupWatch(dir, depth)
+}
 
+// ErrorCount returns the total number of errors received from fsnotify
+func (fw *FsnotifyWatcher) ErrorCount() uint64 {
+	return atomic.LoadUint64(&fw.errorCount)
+}
 
+// isSubPath reports whether path is located below parent
+func isSubPath(path, parent string) bool {
+	return strings.HasPrefix(path, strings.TrimSuffix(parent, string(filepath.Separator))+string(filepath.Separator))
+}
 
+// Events returns the event channel; it is closed by Close
+func (fw *FsnotifyWatcher) Events() <-chan model.FileEvent {
+	return fw.events
+}
 
+// Close stops monitoring and closes the event channel; it is safe to call more than once
+func (fw *FsnotifyWatcher) Close() error {
+	fw.closeOnce.Do(func() {
+		// mark stopping first to avoid race logging in the event loop
+		atomic.StoreInt32(&fw.stopping, 1)
+		fw.cancel()
+		if fw.fsWatcher != nil {
+			_ = fw.fsWatcher.Close()
+		}
+		fw.wg.Wait()
+		close(fw.events)
+	})
+	return nil
+}
 
 func (fw *FsnotifyWatcher) removeWatchRecursive(path string) {
 	fw.mu.Lock()
@@ -138,7 +209,7 @@ func (fw *FsnotifyWatcher) removeWatchRecursive(path string) {
 	}
 }
 
-func (fw *FsnotifyWatcher) setupWatch(baseDir string) error {
+func (fw *FsnotifyWatcher) setupWatch(baseDir string, depth int) error {
 	info, err := os.Stat(baseDir)
 	if err != nil {
 		return fmt.Errorf("stat failed for %s: %w", baseDir, err)
@@ -146,12 +217,24 @@ func (fw *FsnotifyWatcher) setupWatch(baseDir string) error {
 	if !info.IsDir() {
 		return fmt.Errorf("%s is not a directory", baseDir)
 	}
+	return fw.addWatches(baseDir, depth, nil)
+}
 
-	err = filepath.Walk(baseDir, func(p string, info os.FileInfo, err error) error {
+// addWatches registers root and its subdirectories down to depth; onFile, if set,
+// is called for every regular file found on the way
+func (fw *FsnotifyWatcher) addWatches(root string, depth int, onFile func(path string)) error {
+	return filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if info.Mode().IsRegular() && onFile != nil {
+			onFile(p)
+			return nil
+		}
 		if info.IsDir() {
+			if !withinDepth(root, p, depth) {
+				return filepath.SkipDir
+			}
 			fw.mu.RLock()
 			_, alreadyWatched := fw.watchedDirs[p]
 			fw.mu.RUnlock()
@@ -168,10 +251,52 @@ func (fw *FsnotifyWatcher) setupWatch(baseDir string) error {
 		}
 		return nil
 	})
-	return err
 }
 
+// remainingDepth returns how many levels below dir are still covered by a base
+// directory, or false when dir lies outside every base directory's depth
+func (fw *FsnotifyWatcher) remainingDepth(dir string) (int, bool) {
+	fw.mu.RLock()
+	defer fw.mu.RUnlock()
 
+	remaining, covered := 0, false
+	for base, depth := range fw.baseDirs {
+		d := dirDepth(base, dir)
+		if d < 0 || (depth != config.UnlimitedDepth && d > depth) {
+			continue
+		}
+		covered = true
+		if depth == config.UnlimitedDepth {
+			return config.UnlimitedDepth, true
+		}
+		if depth-d > remaining {
+			remaining = depth - d
+		}
+	}
+	return remaining, covered
+}
+
+// watchNewDirectory adds watches for a directory created (or moved) inside a watched
+// tree and reports the files it already contains, which fsnotify never saw
+func (fw *FsnotifyWatcher) watchNewDirectory(dir string) {
+	depth, ok := fw.remainingDepth(dir)
+	if !ok {
+		return
+	}
+	err := fw.addWatches(dir, depth, func(path string) {
+		fw.send(model.FileEvent{
+			Type:      model.FileCreated,
+			Path:      path,
+			Directory: filepath.Dir(path),
+			Timestamp: time.Now(),
+		})
+	})
+	if err != nil {
+		fw.logger.WithFields(logger.String("dir", dir), logger.Err(err)).Warn("[FsnotifyWatcher] Failed to watch new directory")
+		return
+	}
+	fw.logger.WithFields(logger.String("dir", dir)).Info("[FsnotifyWatcher] 新建子目录已加入监控")
+}
 
 func (fw *FsnotifyWatcher) processEvents() {
 	defer fw.wg.Done()
@@ -190,7 +315,8 @@ func (fw *FsnotifyWatcher) processEvents() {
 			if !ok {
 				return
 			}
-			fw.logger.Error("[FsnotifyWatcher] Monitor error: %v", err)
+			atomic.AddUint64(&fw.errorCount, 1)
+			fw.logger.WithFields(logger.Err(err)).Error("[FsnotifyWatcher] Monitor error")
 		}
 	}
 }
@@ -200,10 +326,6 @@ func (fw *FsnotifyWatcher) handleFsnotifyEvent(event fsnotify.Event) {
 		return
 	}
 
-	if fw.shouldIgnoreFile(event.Name) {
-		return
-	}
-
 	var eventType model.FileEventType
 	var oldPath string
 	switch {
@@ -219,28 +341,37 @@ func (fw *FsnotifyWatcher) handleFsnotifyEvent(event fsnotify.Event) {
 		fw.mu.Unlock()
 		if paired {
 			eventType = model.FileRenamed
-			fw.logger.Info("[FsnotifyWatcher] 检测到重命名事件(配对): %s -> %s", oldPath, event.Name)
+			fw.logger.WithFields(logger.String("path", event.Name), logger.String("old_path", oldPath)).
+				Info("[FsnotifyWatcher] 检测到重命名事件(配对)")
 		} else {
 			eventType = model.FileCreated
-			fw.logger.Info("[FsnotifyWatcher] 检测到文件创建事件: %s", event.Name)
+			fw.logger.WithFields(logger.String("path", event.Name)).Info("[FsnotifyWatcher] 检测到文件创建事件")
+		}
+		if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+			fw.watchNewDirectory(event.Name)
 		}
 	case event.Op&fsnotify.Write == fsnotify.Write:
 		eventType = model.FileModified
-		fw.logger.Info("[FsnotifyWatcher] 检测到文件修改事件: %s", event.Name)
+		fw.logger.WithFields(logger.String("path", event.Name)).Info("[FsnotifyWatcher] 检测到文件修改事件")
 	case event.Op&fsnotify.Remove == fsnotify.Remove:
 		eventType = model.FileDeleted
-		fw.logger.Info("[FsnotifyWatcher] 检测到文件删除事件: %s", event.Name)
+		fw.logger.WithFields(logger.String("path", event.Name)).Info("[FsnotifyWatcher] 检测到文件删除事件")
+		fw.removeWatchRecursive(event.Name)
+	case event.Op&fsnotify.Rename == fsnotify.Rename:
+		// The old name is gone; wait for the CREATE of the new name to emit a paired
+		// renamed event, or report a deletion if the file moved out of the watched tree.
+		fw.logger.WithFields(logger.String("path", event.Name)).Info("[FsnotifyWatcher] 检测到重命名事件，等待配对")
 		dir := filepath.Dir(event.Name)
 		fw.mu.Lock()
 		fw.movePairs[dir] = renamePair{old: event.Name, ts: time.Now()}
 		fw.mu.Unlock()
 		fw.removeWatchRecursive(event.Name)
-	case event.Op&fsnotify.Rename == fsnotify.Rename:
-		eventType = model.FileRenamed
-		fw.logger.Info("[FsnotifyWatcher] 检测到重命名事件: %s", event.Name)
-		fw.removeWatchRecursive(event.Name)
+		fw.wg.Add(1)
+		go fw.expireMovePair(dir, event.Name)
+		return
 	default:
-		fw.logger.Info("[FsnotifyWatcher] 未识别的事件类型，忽略: %s", event.Op)
+		fw.logger.WithFields(logger.String("path", event.Name), logger.String("op", event.Op.String())).
+			Info("[FsnotifyWatcher] 未识别的事件类型，忽略")
 		return
 	}
 
@@ -253,41 +384,47 @@ func (fw *FsnotifyWatcher) handleFsnotifyEvent(event fsnotify.Event) {
 	if eventType == model.FileRenamed && oldPath != "" {
 		fileEvent.OldPath = oldPath
 	}
+	fw.send(fileEvent)
+}
+
+// expireMovePair reports a renamed-away path as deleted when no CREATE pairs with it in time
+func (fw *FsnotifyWatcher) expireMovePair(dir, oldPath string) {
+	defer fw.wg.Done()
 
+	timer := time.NewTimer(movePairWindow)
+	defer timer.Stop()
 	select {
-	case fw.events <- fileEvent:
-		fw.logger.Info("[FsnotifyWatcher] 文件事件已发送到事件通道: %s (类型: %s)", event.Name, eventType)
-	default:
-		fw.logger.Info("[FsnotifyWatcher] 事件通道已满，丢弃事件: %s (类型: %s)", event.Name, eventType)
+	case <-fw.ctx.Done():
+		return
+	case <-timer.C:
 	}
 
-	fw.dispatchEvent(fileEvent)
-}
-
-func (fw *FsnotifyWatcher) shouldIgnoreFile(path string) bool {
-	base := filepath.Base(path)
-	if strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") || strings.HasSuffix(base, ".tmp") {
-		return true
-	}
-	if strings.HasSuffix(base, ".swp") || strings.HasSuffix(base, ".swo") || strings.HasSuffix(base, ".swn") {
-		return true
+	fw.mu.Lock()
+	p, ok := fw.movePairs[dir]
+	if ok && p.old == oldPath {
+		delete(fw.movePairs, dir)
 	}
-	if strings.HasSuffix(base, ".lock") || strings.HasSuffix(base, ".bak") {
-		return true
+	fw.mu.Unlock()
+	if !ok || p.old != oldPath || atomic.LoadInt32(&fw.stopping) == 1 {
+		return
 	}
-	return false
-}
 
-// dispatchEvent Dispatch event to all handlers
-func (fw *FsnotifyWatcher) dispatchEvent(event model.FileEvent) {
-	fw.mu.RLock()
-	handlers := make([]handlerEntry, len(fw.handlers))
-	copy(handlers, fw.handlers)
-	fw.mu.RUnlock()
+	fw.logger.WithFields(logger.String("path", oldPath)).Info("[FsnotifyWatcher] 重命名未配对，视为文件删除")
+	fw.send(model.FileEvent{
+		Type:      model.FileDeleted,
+		Path:      oldPath,
+		Directory: dir,
+		Timestamp: time.Now(),
+	})
+}
 
-	for _, h := range handlers {
-		if strings.HasPrefix(event.Path, h.baseDir) {
-			h.fn(event)
-		}
+// send delivers an event without blocking; events are dropped when the channel is full
+func (fw *FsnotifyWatcher) send(fileEvent model.FileEvent) {
+	select {
+	case fw.events <- fileEvent:
+		fw.logger.WithFields(eventFields(fileEvent)...).Debug("[FsnotifyWatcher] 文件事件已发送到事件通道")
+	default:
+		metricEventsDropped.Inc(dropStageWatcher)
+		fw.logger.WithFields(eventFields(fileEvent)...).Info("[FsnotifyWatcher] 事件通道已满，丢弃事件")
 	}
 }
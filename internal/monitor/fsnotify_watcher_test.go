@@ -0,0 +1,91 @@
+package monitor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+func TestFsnotifyWatcherNewSubdirectory(t *testing.T) {
+	root := t.TempDir()
+	fw := NewFsnotifyWatcher(logger.NewLogger(logger.ERROR, io.Discard))
+	if fw == nil {
+		t.Skip("fsnotify is not available")
+	}
+	defer fw.Close()
+	if err := fw.Watch(root, config.UnlimitedDepth); err != nil {
+		t.Fatal(err)
+	}
+
+	sub := filepath.Join(root, "incoming", "2026")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(sub, "data.csv")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	timeout := time.After(3 * time.Second)
+	for {
+		select {
+		case event := <-fw.Events():
+			if event.Path == file {
+				if !fw.IsWatching(sub) {
+					t.Errorf("%s should be watched", sub)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no event for %s", file)
+		}
+	}
+}
+
+func TestFsnotifyWatcherDeleteThenCreate(t *testing.T) {
+	root := t.TempDir()
+	oldFile := filepath.Join(root, "a.csv")
+	if err := os.WriteFile(oldFile, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fw := NewFsnotifyWatcher(logger.NewLogger(logger.ERROR, io.Discard))
+	if fw == nil {
+		t.Skip("fsnotify is not available")
+	}
+	defer fw.Close()
+	if err := fw.Watch(root, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(oldFile); err != nil {
+		t.Fatal(err)
+	}
+	newFile := filepath.Join(root, "b.csv")
+	if err := os.WriteFile(newFile, []byte("y"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]model.FileEventType{oldFile: model.FileDeleted, newFile: model.FileCreated}
+	timeout := time.After(3 * time.Second)
+	for len(want) > 0 {
+		select {
+		case event := <-fw.Events():
+			expected, ok := want[event.Path]
+			if !ok {
+				continue
+			}
+			if event.Type != expected || event.OldPath != "" {
+				t.Fatalf("event for %s = %s (old path %q), want %s", event.Path, event.Type, event.OldPath, expected)
+			}
+			delete(want, event.Path)
+		case <-timeout:
+			t.Fatalf("missing events: %v", want)
+		}
+	}
+}
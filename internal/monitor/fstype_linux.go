@@ -0,0 +1,31 @@
+//go:build linux
+
+package monitor
+
+import (
+	"syscall"
+)
+
+// statfs f_type magic numbers of filesystems whose remote changes inotify cannot see.
+// Keyed by uint32: f_type is a signed word on 32-bit platforms, and widening it to int64
+// would sign-extend the cifs and smb2 magic numbers.
+var networkFilesystems = map[uint32]string{
+	0x6969:     "nfs",
+	0x517B:     "smb",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x65735546: "fuse",
+	0x01021997: "9p",
+	0x00C36400: "ceph",
+}
+
+// networkFilesystem reports whether dir lives on a network or FUSE filesystem
+// and returns its type name.
+func networkFilesystem(dir string) (string, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return "", false, err
+	}
+	name, ok := networkFilesystems[uint32(st.Type)]
+	return name, ok, nil
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package monitor
+
+import "testing"
+
+func TestNetworkFilesystemMagicSignedType(t *testing.T) {
+	// 32 位平台上 Statfs_t.Type 为 int32，cifs 与 smb2 的魔数为负值
+	for _, tc := range []struct {
+		typ  int32
+		want string
+	}{
+		{-0xACB2BE, "cifs"},
+		{-0x1ACB2BE, "smb2"},
+		{0x6969, "nfs"},
+	} {
+		if got := networkFilesystems[uint32(tc.typ)]; got != tc.want {
+			t.Errorf("type %#x: got %q, want %q", uint32(tc.typ), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package monitor
+
+import "errors"
+
+// networkFilesystem is only implemented for Linux statfs magic numbers; watcher auto
+// falls back to inotify elsewhere.
+func networkFilesystem(dir string) (string, bool, error) {
+	return "", false, errors.New("filesystem type detection requires linux")
+}
@@ -0,0 +1,273 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"dir-monitor-go/internal/config"
+)
+
+const (
+	// 事件处理器空闲时的心跳间隔
+	ProcessorHeartbeatInterval = 5 * time.Second
+	// 执行超过超时时间多久后视为挂起（超时后仍需时间终止进程树）
+	HungExecutionGrace = 30 * time.Second
+)
+
+// 健康检查状态
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Critical 为 true 的检查失败时存活检查（/healthz）失败
+	Critical bool `json:"critical,omitempty"`
+}
+
+// HealthReport 一次健康检查的汇总结果
+type HealthReport struct {
+	Status    string        `json:"status"`
+	Live      bool          `json:"live"`
+	Ready     bool          `json:"ready"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+// newHealthReport 根据检查项计算整体状态
+func newHealthReport(checks []HealthCheck, checkedAt time.Time) HealthReport {
+	report := HealthReport{
+		Status:    HealthStatusOK,
+		Live:      true,
+		Ready:     true,
+		CheckedAt: checkedAt,
+		Checks:    checks,
+	}
+	for _, c := range checks {
+		if c.Status == HealthStatusOK {
+			continue
+		}
+		report.Status = HealthStatusFail
+		report.Ready = false
+		if c.Critical {
+			report.Live = false
+		}
+	}
+	return report
+}
+
+// runningExecution 正在执行的命令，用于挂起检测
+type runningExecution struct {
+	monitorID string
+	path      string
+	start     time.Time
+	timeout   time.Duration
+}
+
+func (m *Monitor) trackExecution(monitor config.Monitor, path string, start time.Time) uint64 {
+	m.runningMu.Lock()
+	defer m.runningMu.Unlock()
+	m.runningSeq++
+	m.running[m.runningSeq] = runningExecution{
+		monitorID: monitorKey(monitor),
+		path:      path,
+		start:     start,
+		timeout:   time.Duration(monitor.Timeout) * time.Second,
+	}
+	return m.runningSeq
+}
+
+func (m *Monitor) untrackExecution(id uint64) {
+	m.runningMu.Lock()
+	delete(m.running, id)
+	m.runningMu.Unlock()
+}
+
+func (m *Monitor) markProcessorProgress() {
+	atomic.StoreInt64(&m.processorBeat, time.Now().UnixNano())
+}
+
+// healthInterval 返回健康检查间隔
+func (m *Monitor) healthInterval() time.Duration {
+	interval := time.Duration(m.currentConfig().Settings.HealthCheckIntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = time.Duration(config.DefaultHealthCheckIntervalSeconds) * time.Second
+	}
+	return interval
+}
+
+// healthDaemon 按 health_check_interval_seconds 周期执行健康检查
+func (m *Monitor) healthDaemon() {
+	defer m.wg.Done()
+
+	lastErrors := m.watchErrorCount()
+	m.storeHealth(m.checkHealth(0))
+
+	interval := m.healthInterval()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-m.stopChan:
+			return
+		case <-timer.C:
+			errors := m.watchErrorCount()
+			report := m.checkHealth(errors - lastErrors)
+			lastErrors = errors
+			m.storeHealth(report)
+			if report.Status != HealthStatusOK {
+				m.logger.Warn("[Health] 健康检查未通过: %s", summarizeFailures(report))
+			} else {
+				m.logger.Debug("[Health] 健康检查通过")
+			}
+			// 间隔可能在热重载后变化
+			timer.Reset(m.healthInterval())
+		}
+	}
+}
+
+func (m *Monitor) storeHealth(report HealthReport) {
+	m.healthMu.Lock()
+	m.health = &report
+	m.healthMu.Unlock()
+}
+
+// HealthReport 返回最近一次健康检查结果；尚未检查或已停止时返回未就绪
+func (m *Monitor) HealthReport() HealthReport {
+	if atomic.LoadInt32(&m.stopped) == 1 {
+		return newHealthReport([]HealthCheck{{
+			Name: "monitor", Status: HealthStatusFail, Message: "monitor stopped", Critical: true,
+		}}, time.Now())
+	}
+
+	m.healthMu.RLock()
+	defer m.healthMu.RUnlock()
+	if m.health == nil {
+		return newHealthReport([]HealthCheck{{
+			Name: "monitor", Status: HealthStatusFail, Message: "monitor not started",
+		}}, time.Now())
+	}
+	return *m.health
+}
+
+// checkHealth 执行一次全部检查，newErrors 为上次检查以来的 fsnotify 错误数
+func (m *Monitor) checkHealth(newErrors uint64) HealthReport {
+	now := time.Now()
+	cfg := m.currentConfig()
+
+	var checks []HealthCheck
+	checks = append(checks, m.checkDirectories(cfg)...)
+	checks = append(checks, m.checkProcessor(now))
+	checks = append(checks, m.checkWatcherErrors(newErrors, cfg.Settings.HealthErrorThreshold))
+	checks = append(checks, m.checkHungExecutions(now))
+
+	return newHealthReport(checks, now)
+}
+
+// checkDirectories 检查每个配置目录仍然存在且已注册到 fsnotify，按需自动恢复监控
+func (m *Monitor) checkDirectories(cfg *config.Config) []HealthCheck {
+	dirs := make([]string, 0)
+	for dir := range enabledDirectories(cfg) {
+		dirs = append(dirs, dir)
+	}
+	if m.specificDir != "" {
+		dirs = []string{m.specificDir}
+	}
+	sort.Strings(dirs)
+
+	checks := make([]HealthCheck, 0, len(dirs))
+	for _, dir := range dirs {
+		check := HealthCheck{Name: "directory:" + dir, Status: HealthStatusOK}
+
+		info, err := os.Stat(dir)
+		switch {
+		case err != nil:
+			check.Status = HealthStatusFail
+			check.Message = fmt.Sprintf("directory unavailable: %v", err)
+		case !info.IsDir():
+			check.Status = HealthStatusFail
+			check.Message = "path is not a directory"
+		case !m.isWatching(dir):
+			check.Status = HealthStatusFail
+			check.Message = "directory is not registered with the watcher"
+			if cfg.Settings.HealthAutoRewatch {
+				if err := m.rewatchDirectory(dir); err != nil {
+					check.Message += fmt.Sprintf("; re-watch failed: %v", err)
+				} else {
+					m.logger.Warn("[Health] 目录监控已丢失，已自动恢复: %s", dir)
+					check.Status = HealthStatusOK
+					check.Message = "watch re-established"
+				}
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkProcessor 检查事件处理协程仍在推进
+func (m *Monitor) checkProcessor(now time.Time) HealthCheck {
+	check := HealthCheck{Name: "event_processor", Status: HealthStatusOK, Critical: true}
+
+	limit := m.healthInterval()
+	if limit < 3*ProcessorHeartbeatInterval {
+		limit = 3 * ProcessorHeartbeatInterval
+	}
+
+	last := time.Unix(0, atomic.LoadInt64(&m.processorBeat))
+	if since := now.Sub(last); since > limit {
+		check.Status = HealthStatusFail
+		check.Message = fmt.Sprintf("no progress for %v (queued events: %d)", since.Truncate(time.Second), len(m.eventChannel))
+	}
+	return check
+}
+
+// checkWatcherErrors 检查 fsnotify 错误通道是否在刷屏
+func (m *Monitor) checkWatcherErrors(newErrors uint64, threshold int) HealthCheck {
+	check := HealthCheck{Name: "watcher_errors", Status: HealthStatusOK}
+	if threshold > 0 && newErrors >= uint64(threshold) {
+		check.Status = HealthStatusFail
+		check.Message = fmt.Sprintf("%d fsnotify errors since last check (threshold %d)", newErrors, threshold)
+	}
+	return check
+}
+
+// checkHungExecutions 检查是否有执行超过超时时间仍未结束
+func (m *Monitor) checkHungExecutions(now time.Time) HealthCheck {
+	check := HealthCheck{Name: "executions", Status: HealthStatusOK}
+
+	m.runningMu.Lock()
+	var hung []string
+	for _, exec := range m.running {
+		if exec.timeout > 0 && now.Sub(exec.start) > exec.timeout+HungExecutionGrace {
+			hung = append(hung, fmt.Sprintf("%s(%s, running %v)", exec.monitorID, exec.path, now.Sub(exec.start).Truncate(time.Second)))
+		}
+	}
+	m.runningMu.Unlock()
+
+	if len(hung) > 0 {
+		sort.Strings(hung)
+		check.Status = HealthStatusFail
+		check.Message = "hung executions: " + strings.Join(hung, ", ")
+	}
+	return check
+}
+
+func summarizeFailures(report HealthReport) string {
+	var parts []string
+	for _, c := range report.Checks {
+		if c.Status != HealthStatusOK {
+			parts = append(parts, c.Name+": "+c.Message)
+		}
+	}
+	return strings.Join(parts, "; ")
+}
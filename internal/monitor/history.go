@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/history"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// 执行记录存放在 data_dir 下的子目录
+	HistorySubdir = "history"
+	// 执行记录保留期清理间隔
+	HistoryPruneInterval = 1 * time.Hour
+)
+
+// HistoryDir 返回执行记录目录（data_dir 为相对路径时相对于工作目录）
+func HistoryDir(cfg *config.Config) string {
+	dataDir := cfg.Settings.DataDir
+	if dataDir == "" {
+		dataDir = config.DefaultDataDir
+	}
+	return filepath.Join(dataDir, HistorySubdir)
+}
+
+// openHistory 打开执行记录存储；失败时仅告警，不影响监控运行
+func openHistory(cfg *config.Config, log *logger.Logger) *history.Store {
+	retention := time.Duration(cfg.Settings.HistoryRetentionDays) * 24 * time.Hour
+	store, err := history.Open(HistoryDir(cfg), retention, cfg.Settings.HistoryOutputMaxBytes)
+	if err != nil {
+		log.Warn("[Monitor] 打开执行记录存储失败，将不记录执行历史: %v", err)
+		return nil
+	}
+	return store
+}
+
+// recordHistory 记录一次执行尝试
+func (m *Monitor) recordHistory(monitor config.Monitor, executor *CommandExecutor, event *model.FileEvent, attempt int, start time.Time, result *ExecResult, err error) {
+	if m.history == nil {
+		return
+	}
+
+	rec := history.Record{
+		MonitorID: monitorKey(monitor),
+		Paths:     executor.paths(event),
+		EventType: string(event.Type),
+		Attempt:   attempt,
+		Start:     start,
+		End:       time.Now(),
+		Status:    historyStatus(err),
+		ExitCode:  -1,
+		TimedOut:  errors.Is(err, ErrCommandTimeout),
+	}
+	if err == nil {
+		rec.ExitCode = 0
+	} else {
+		rec.Error = err.Error()
+	}
+	if result != nil {
+		rec.Start = result.Start
+		rec.End = result.End
+		rec.ExitCode = result.ExitCode
+		rec.Stdout = result.Stdout
+		rec.Stderr = result.Stderr
+		rec.OutputFile = result.OutputFile
+	}
+
+	if err := m.history.Append(rec); err != nil {
+		m.logger.WithFields(logger.Err(err)).Warn("[Monitor] 写入执行记录失败")
+	}
+}
+
+// historyStatus 将执行错误映射为执行记录状态
+func historyStatus(err error) string {
+	switch {
+	case err == nil:
+		return history.StatusSuccess
+	case errors.Is(err, ErrCommandTimeout):
+		return history.StatusTimeout
+	default:
+		return history.StatusFailure
+	}
+}
+
+// pruneHistory 按保留期清理执行记录，最多每 HistoryPruneInterval 执行一次
+func (m *Monitor) pruneHistory(now time.Time) {
+	if m.history == nil || now.Sub(m.lastHistoryPrune) < HistoryPruneInterval {
+		return
+	}
+	m.lastHistoryPrune = now
+
+	removed, err := m.history.Prune(now)
+	if err != nil {
+		m.logger.Warn("[Monitor] 清理执行记录失败: %v", err)
+		return
+	}
+	if removed > 0 {
+		m.logger.Info("[Monitor] 已清理 %d 个过期执行记录文件", removed)
+	}
+}
@@ -0,0 +1,102 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/ignore"
+	"dir-monitor-go/internal/logger"
+)
+
+// buildIgnoreMatchers 按监控项编译忽略规则：全局规则、监控项规则、监控目录中的 .dirmonitorignore
+func buildIgnoreMatchers(cfg *config.Config, log *logger.Logger) map[string]*ignore.Matcher {
+	matchers := make(map[string]*ignore.Matcher, len(cfg.Monitors))
+	for _, monitor := range cfg.Monitors {
+		patterns := cfg.IgnorePatterns(monitor)
+		base, err := ignore.Compile(patterns)
+		if err != nil {
+			// Validate 已检查配置中的规则，此处不应出错
+			log.Error("[Monitor] 编译忽略规则失败: %s: %v", monitor.Directory, err)
+			continue
+		}
+
+		fileName := filepath.Join(monitor.Directory, ignore.FileName)
+		lines, err := ignore.ReadFile(fileName)
+		if err != nil {
+			log.Warn("[Monitor] 读取忽略规则文件失败: %s: %v", fileName, err)
+		}
+		matcher := base
+		if len(lines) > 0 {
+			if withFile, err := ignore.Compile(append(patterns, lines...)); err != nil {
+				log.Warn("[Monitor] 忽略规则文件无效，仅使用配置中的规则: %s: %v", fileName, err)
+			} else {
+				matcher = withFile
+			}
+		}
+		matchers[monitorKey(monitor)] = matcher
+	}
+	return matchers
+}
+
+// reloadIgnoreRules 重新编译当前配置的忽略规则
+func (m *Monitor) reloadIgnoreRules(cfg *config.Config) {
+	matchers := buildIgnoreMatchers(cfg, m.logger)
+	m.ignoreMu.Lock()
+	m.ignoreMatchers = matchers
+	m.ignoreMu.Unlock()
+}
+
+// isIgnored 判断 path 是否被监控项的忽略规则排除
+func (m *Monitor) isIgnored(monitor config.Monitor, path string) bool {
+	m.ignoreMu.RLock()
+	matcher := m.ignoreMatchers[monitorKey(monitor)]
+	m.ignoreMu.RUnlock()
+	if matcher.Len() == 0 {
+		return false
+	}
+	isDir := false
+	if info, err := os.Lstat(path); err == nil {
+		isDir = info.IsDir()
+	}
+	return matcher.Ignored(monitor.Directory, path, isDir)
+}
+
+// ignoredByAll 判断 path 是否被所有覆盖它的启用监控项忽略；没有监控项覆盖时返回 false
+func (m *Monitor) ignoredByAll(path string) bool {
+	covered := false
+	for _, monitor := range m.currentConfig().Monitors {
+		if !monitor.Enabled || !isWithinDir(monitor.Directory, path) {
+			continue
+		}
+		if !m.isIgnored(monitor, path) {
+			return false
+		}
+		covered = true
+	}
+	return covered
+}
+
+// isWithinDir 判断 path 是否位于 dir 之下
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isIgnoreFile 判断 path 是否为监控目录中的忽略规则文件
+func (m *Monitor) isIgnoreFile(path string) bool {
+	if filepath.Base(path) != ignore.FileName {
+		return false
+	}
+	dir := filepath.Dir(path)
+	for _, monitor := range m.currentConfig().Monitors {
+		if monitor.Directory == dir {
+			return true
+		}
+	}
+	return false
+}
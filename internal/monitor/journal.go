@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/journal"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// JournalSubdir 预写日志在 data_dir 下的子目录
+	JournalSubdir = "journal"
+	// JournalDrainInterval 检查日志中暂存事件的间隔
+	JournalDrainInterval = time.Second
+
+	// 每次从日志取回的事件数
+	journalDrainBatch = 100
+)
+
+// JournalDir 返回配置对应的预写日志目录
+func JournalDir(cfg *config.Config) string {
+	dataDir := cfg.Settings.DataDir
+	if dataDir == "" {
+		dataDir = config.DefaultDataDir
+	}
+	return filepath.Join(dataDir, JournalSubdir)
+}
+
+// openJournal 在 journal_enabled 时打开预写日志；失败时告警并退回纯内存处理
+func openJournal(cfg *config.Config, log *logger.Logger) *journal.Journal {
+	if !cfg.Settings.JournalEnabled {
+		return nil
+	}
+	j, err := journal.Open(JournalDir(cfg), cfg.Settings.JournalCompactThreshold)
+	if err != nil {
+		log.Error("[Monitor] 无法打开事件预写日志，事件将只保存在内存中: %v", err)
+		return nil
+	}
+	if pending := j.Pending(); pending > 0 {
+		log.Info("[Monitor] 预写日志中有 %d 个未确认的事件，将重新处理", pending)
+	}
+	return j
+}
+
+// journalEvent 将事件写入预写日志并设置序号；失败时事件仍按内存方式处理
+func (m *Monitor) journalEvent(event *model.FileEvent) {
+	if m.journal == nil || event.Synthetic {
+		return
+	}
+	seq, err := m.journal.Append(*event)
+	if err != nil {
+		m.logger.WithFields(append(eventFields(*event), logger.Err(err))...).Error("[Monitor] 写入事件预写日志失败")
+		return
+	}
+	event.Seq = seq
+}
+
+// spillEvent 事件通道已满时将已写入日志的事件留在磁盘，稍后由 drainJournal 投递
+func (m *Monitor) spillEvent(event model.FileEvent) bool {
+	if m.journal == nil || event.Seq == 0 {
+		return false
+	}
+	m.journal.Release(event.Seq)
+	metricEventsSpilled.Inc()
+	select {
+	case m.spillSignal <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// ackEvents 确认事件已处理完成
+func (m *Monitor) ackEvents(seqs ...uint64) {
+	if m.journal == nil {
+		return
+	}
+	acks := make([]uint64, 0, len(seqs))
+	for _, seq := range seqs {
+		if seq != 0 {
+			acks = append(acks, seq)
+		}
+	}
+	if len(acks) == 0 {
+		return
+	}
+	if err := m.journal.Ack(acks...); err != nil {
+		m.logger.WithFields(logger.Err(err)).Error("[Monitor] 写入事件确认失败，事件可能在重启后重复处理")
+	}
+}
+
+// drainJournal 启动时重放未确认的事件，之后持续投递因通道已满暂存的事件
+func (m *Monitor) drainJournal() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(JournalDrainInterval)
+	defer ticker.Stop()
+
+	for {
+		if !m.deliverJournaled() {
+			return
+		}
+		select {
+		case <-m.stopChan:
+			return
+		case <-m.spillSignal:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverJournaled 将日志中未投递的事件阻塞地送入事件通道，监控停止时返回 false
+func (m *Monitor) deliverJournaled() bool {
+	for {
+		events, err := m.journal.Undelivered(journalDrainBatch)
+		if err != nil {
+			m.logger.WithFields(logger.Err(err)).Error("[Monitor] 读取事件预写日志失败")
+			return true
+		}
+		if len(events) == 0 {
+			return true
+		}
+		for i, event := range events {
+			select {
+			case m.eventChannel <- event:
+				m.logger.WithFields(eventFields(event)...).Debug("[Monitor] 预写日志中的事件已投递")
+			case <-m.stopChan:
+				// 未投递的事件仍在日志中，下次启动时重放
+				for _, rest := range events[i:] {
+					m.journal.Release(rest.Seq)
+				}
+				return false
+			}
+		}
+	}
+}
+
+// ackTracker 跟踪一次目录处理触发的全部命令执行，全部完成后确认对应事件；
+// 有执行因监控停止被取消时不确认，事件在重启后重放
+type ackTracker struct {
+	wg        sync.WaitGroup
+	cancelled int32
+}
+
+func (t *ackTracker) add() {
+	if t != nil {
+		t.wg.Add(1)
+	}
+}
+
+func (t *ackTracker) done(completed bool) {
+	if t == nil {
+		return
+	}
+	if !completed {
+		atomic.StoreInt32(&t.cancelled, 1)
+	}
+	t.wg.Done()
+}
+
+// ackWhenDone 等待 tracker 中的执行结束后释放一个缓冲区对 seqs 的引用
+func (m *Monitor) ackWhenDone(t *ackTracker, seqs []uint64) {
+	if m.journal == nil || len(seqs) == 0 {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		t.wg.Wait()
+		if atomic.LoadInt32(&t.cancelled) == 1 {
+			m.logger.Info("[Monitor] 命令执行被取消，%d 个事件保留在预写日志中", len(seqs))
+			return
+		}
+		m.dirMu.Lock()
+		acks := m.releaseSeqsLocked(seqs)
+		m.dirMu.Unlock()
+		m.ackEvents(acks...)
+	}()
+}
+
+// releaseSeqsLocked 释放一个缓冲区对 seqs 的引用，返回不再被任何缓冲区引用、需要确认的事件；
+// 调用方持有 dirMu，释放后再调用 ackEvents，写入日志时不阻塞事件聚合
+func (m *Monitor) releaseSeqsLocked(seqs []uint64) []uint64 {
+	acks := make([]uint64, 0, len(seqs))
+	for _, seq := range seqs {
+		if seq == 0 {
+			continue
+		}
+		if m.seqRefs[seq] > 1 {
+			m.seqRefs[seq]--
+			continue
+		}
+		delete(m.seqRefs, seq)
+		acks = append(acks, seq)
+	}
+	return acks
+}
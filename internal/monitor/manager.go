@@ -2,8 +2,11 @@ package monitor
 
 import (
 	"context"
+	"sort"
 	"sync"
+	"time"
 
+	"dir-monitor-go/internal/config"
 	"dir-monitor-go/internal/logger"
 )
 
@@ -65,6 +68,63 @@ func (mm *MonitorManager) Stop(ctx context.Context) error {
 	return nil
 }
 
+// Reload 将新配置应用到所有监控器，任一监控器拒绝时返回错误
+func (mm *MonitorManager) Reload(cfg *config.Config) error {
+	mm.mu.Lock()
+	snapshot := make([]*Monitor, len(mm.monitors))
+	copy(snapshot, mm.monitors)
+	mm.mu.Unlock()
+
+	var reloadErr error
+	for _, monitor := range snapshot {
+		if err := monitor.Reload(cfg); err != nil {
+			mm.logger.Error("[MonitorManager] Failed to reload monitor: %v", err)
+			reloadErr = err
+		}
+	}
+	return reloadErr
+}
+
+// HealthReport 汇总所有监控器的健康检查结果
+func (mm *MonitorManager) HealthReport() HealthReport {
+	mm.mu.Lock()
+	snapshot := make([]*Monitor, len(mm.monitors))
+	copy(snapshot, mm.monitors)
+	mm.mu.Unlock()
+
+	if len(snapshot) == 0 {
+		return newHealthReport([]HealthCheck{{
+			Name: "monitor", Status: HealthStatusFail, Message: "no monitors running", Critical: true,
+		}}, time.Now())
+	}
+
+	var checks []HealthCheck
+	checkedAt := time.Time{}
+	for _, monitor := range snapshot {
+		report := monitor.HealthReport()
+		checks = append(checks, report.Checks...)
+		if report.CheckedAt.After(checkedAt) {
+			checkedAt = report.CheckedAt
+		}
+	}
+	return newHealthReport(checks, checkedAt)
+}
+
+// PendingItems 汇总所有监控器中延后执行的事件，按到期时间排序
+func (mm *MonitorManager) PendingItems() []PendingItem {
+	mm.mu.Lock()
+	snapshot := make([]*Monitor, len(mm.monitors))
+	copy(snapshot, mm.monitors)
+	mm.mu.Unlock()
+
+	var items []PendingItem
+	for _, monitor := range snapshot {
+		items = append(items, monitor.PendingItems()...)
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Due.Before(items[j].Due) })
+	return items
+}
+
 // cleanupResources 清理资源
 func (mm *MonitorManager) cleanupResources() {
 	mm.mu.Lock()
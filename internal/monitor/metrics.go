@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"errors"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/metrics"
+)
+
+// 执行结果标签值
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+	resultTimeout = "timeout"
+)
+
+// 事件丢弃位置标签值
+const (
+	dropStageMonitor = "monitor"
+	dropStageWatcher = "watcher"
+)
+
+var (
+	metricEventsReceived = metrics.Default.NewCounterVec("dirmon_events_received_total",
+		"File events received, by event type and watched directory.", "type", "directory")
+	metricEventsDropped = metrics.Default.NewCounterVec("dirmon_events_dropped_total",
+		"File events dropped because a channel was full, by stage (monitor or watcher).", "stage")
+	metricEventsSpilled = metrics.Default.NewCounterVec("dirmon_events_spilled_total",
+		"File events kept in the journal because the event channel was full.")
+	metricDedupHits = metrics.Default.NewCounterVec("dirmon_dedup_hits_total",
+		"Executions skipped by the execution dedup window, by monitor.", "monitor_id")
+	metricFilesRejected = metrics.Default.NewCounterVec("dirmon_files_rejected_total",
+		"Files skipped by attribute filters, by monitor and reason.", "monitor_id", "reason")
+	metricExecutionsStarted = metrics.Default.NewCounterVec("dirmon_executions_started_total",
+		"Command executions started (including retries), by monitor.", "monitor_id")
+	metricExecutionsCompleted = metrics.Default.NewCounterVec("dirmon_executions_completed_total",
+		"Command executions completed, by monitor and result (success, failure, timeout).", "monitor_id", "result")
+	metricExecutionDuration = metrics.Default.NewHistogramVec("dirmon_execution_duration_seconds",
+		"Command execution duration in seconds, by monitor.", metrics.DefaultDurationBuckets, "monitor_id")
+)
+
+// executionResult 将执行错误映射为指标中的结果标签
+func executionResult(err error) string {
+	switch {
+	case err == nil:
+		return resultSuccess
+	case errors.Is(err, ErrCommandTimeout):
+		return resultTimeout
+	default:
+		return resultFailure
+	}
+}
+
+// registerGauges 注册按抓取时计算的监控器状态指标
+func (m *Monitor) registerGauges() {
+	metrics.Default.GaugeFunc("dirmon_operation_slots_in_use",
+		"Operation semaphore slots currently held by running commands.",
+		func() float64 { return float64(len(m.opSem)) })
+	metrics.Default.GaugeFunc("dirmon_operation_slots_capacity",
+		"Operation semaphore capacity (max_concurrent_operations).",
+		func() float64 { return float64(cap(m.opSem)) })
+	metrics.Default.GaugeFunc("dirmon_watched_directories",
+		"Configured directories currently watched.",
+		func() float64 {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return float64(len(m.watchedDirs))
+		})
+	metrics.Default.GaugeFunc("dirmon_fsnotify_watches",
+		"Directories (including subdirectories) registered with fsnotify.",
+		func() float64 { return float64(m.backendWatchCount(config.WatcherInotify)) })
+	metrics.Default.GaugeFunc("dirmon_poll_watches",
+		"Directory trees scanned by the polling watcher.",
+		func() float64 { return float64(m.backendWatchCount(config.WatcherPoll)) })
+	metrics.Default.GaugeFunc("dirmon_buffered_directories",
+		"Directories with buffered events waiting for stability.",
+		func() float64 {
+			m.dirMu.Lock()
+			defer m.dirMu.Unlock()
+			dirs := make(map[string]bool)
+			for key, b := range m.buckets {
+				if len(b.events) > 0 {
+					dirs[key.dir] = true
+				}
+			}
+			return float64(len(dirs))
+		})
+	metrics.Default.GaugeFunc("dirmon_buffered_events",
+		"Events buffered while waiting for directory stability.",
+		func() float64 {
+			m.dirMu.Lock()
+			defer m.dirMu.Unlock()
+			n := 0
+			for _, b := range m.buckets {
+				n += len(b.events)
+			}
+			return float64(n)
+		})
+	metrics.Default.GaugeFunc("dirmon_journal_pending",
+		"Journaled events not yet acknowledged.",
+		func() float64 {
+			if m.journal == nil {
+				return 0
+			}
+			return float64(m.journal.Pending())
+		})
+	metrics.Default.GaugeFunc("dirmon_pending_deferred",
+		"Events deferred until their monitor's schedule window opens.",
+		func() float64 {
+			m.pendingMu.Lock()
+			defer m.pendingMu.Unlock()
+			return float64(len(m.pending))
+		})
+	metrics.Default.GaugeFunc("dirmon_event_channel_length",
+		"Events queued in the monitor event channel.",
+		func() float64 { return float64(len(m.eventChannel)) })
+}
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
-	"github.com/adhocore/gronx"
-
 	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/history"
+	"dir-monitor-go/internal/ignore"
+	"dir-monitor-go/internal/journal"
+	"dir-monitor-go/internal/ledger"
 	"dir-monitor-go/internal/logger"
 	"dir-monitor-go/internal/model"
 )
@@ -20,16 +24,25 @@ const (
 	DefaultMaxConcurrentOperations = 5
 	DropLogThrottleTime            = 10 * time.Second
 	CleanupInterval                = 1 * time.Minute
-	DedupCacheExpiration             = 10 * time.Minute
+	DedupCacheExpiration           = 10 * time.Minute
 )
 
 type Monitor struct {
-	config       *config.Config
-	logger       *logger.Logger
-	watcher      *FsnotifyWatcher
-	stopChan     chan struct{}
-	wg           sync.WaitGroup
-	watchedDirs  map[string]bool
+	config *config.Config
+	cfgMu  sync.RWMutex
+	logger *logger.Logger
+	// watchers 按后端名称（inotify/poll）索引的监视器
+	watchers map[string]Watcher
+	clock    Clock
+	// runCommand 执行一次监控项命令，测试中可替换
+	runCommand  func(ctx context.Context, executor *CommandExecutor, monitor config.Monitor, event *model.FileEvent) (*ExecResult, error)
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	watchedDirs map[string]bool
+	// watchBackends 目录实际使用的监控后端（inotify/poll）
+	watchBackends map[string]string
+	// watchDepths 目录注册时的子目录层数
+	watchDepths  map[string]int
 	workingDir   string
 	mu           sync.Mutex
 	eventChannel chan model.FileEvent
@@ -38,9 +51,14 @@ type Monitor struct {
 	dedupCache map[string]time.Time
 	dedupMu    sync.Mutex
 
-	dirTimers  map[string]*time.Timer
-	dirBuffers map[string]map[string]model.FileEvent
-	dirMu      sync.Mutex
+	// buckets 按 (监控项, 目录) 聚合的事件缓冲区
+	buckets map[bucketKey]*aggBucket
+	// seqRefs 日志序号被多少个缓冲区引用，引用全部释放后确认事件
+	seqRefs map[uint64]int
+	dirMu   sync.Mutex
+
+	// handledEvents 已处理完（或在进入处理流程前被丢弃）的事件数
+	handledEvents uint64
 
 	stopped int32
 
@@ -53,6 +71,48 @@ type Monitor struct {
 	opCancel context.CancelFunc
 
 	opSem chan struct{}
+
+	// 健康检查状态
+	processorBeat int64 // 事件处理器最近一次推进的时间（UnixNano）
+	running       map[uint64]runningExecution
+	runningSeq    uint64
+	runningMu     sync.Mutex
+	health        *HealthReport
+	healthMu      sync.RWMutex
+
+	history          *history.Store
+	lastHistoryPrune time.Time
+
+	// ledger 已成功处理的文件，启动扫描据此跳过
+	ledger *ledger.Store
+
+	// journal 事件预写日志（journal_enabled 时），spillSignal 通知有事件暂存到日志
+	journal     *journal.Journal
+	spillSignal chan struct{}
+
+	// pending 不在调度时间内而延后执行的事件，持久化到 pendingPath
+	pending      map[string]PendingItem
+	pendingPath  string
+	pendingTimer Timer
+	pendingMu    sync.Mutex
+
+	// schedules 调度使用的时区与日历，随配置一起替换（受 cfgMu 保护）
+	schedules *scheduleData
+	// filePatterns 按监控项编译的文件模式与触发文件模式，随配置一起替换（受 cfgMu 保护）
+	filePatterns map[string]monitorPatterns
+
+	// awaiting 等待写入完成的文件（监控项 + 路径），受 dirMu 保护
+	awaiting map[string]*readinessWait
+	// closeWrites 跟踪 close_write 策略的 IN_CLOSE_WRITE 事件，未使用该策略时为 nil（受 dirMu 保护）
+	closeWrites *closeWriteTracker
+
+	// ignoreMatchers 按监控项编译的忽略规则
+	ignoreMatchers map[string]*ignore.Matcher
+	ignoreMu       sync.RWMutex
+
+	// selfEvents 文件处置产生变化的路径及忽略截止时间
+	selfEvents map[string]time.Time
+	selfMu     sync.Mutex
 }
 
 func NewMonitor(cfg *config.Config, log *logger.Logger) (*Monitor, error) {
@@ -60,7 +120,30 @@ func NewMonitor(cfg *config.Config, log *logger.Logger) (*Monitor, error) {
 	if watcher == nil {
 		return nil, fmt.Errorf("failed to create FsnotifyWatcher")
 	}
+	poller := NewPollWatcher(log, time.Duration(cfg.Settings.PollIntervalMs)*time.Millisecond)
+
+	return newMonitor(cfg, log, map[string]Watcher{
+		config.WatcherInotify: watcher,
+		config.WatcherPoll:    poller,
+	}, SystemClock{})
+}
+
+// NewMonitorWithWatcher 使用注入的监视器创建 Monitor，所有目录都由该监视器监控
+// （忽略监控项的 watcher 设置）。clock 为 nil 时使用系统时间。
+func NewMonitorWithWatcher(cfg *config.Config, log *logger.Logger, watcher Watcher, clock Clock) (*Monitor, error) {
+	if watcher == nil {
+		return nil, fmt.Errorf("watcher must not be nil")
+	}
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return newMonitor(cfg, log, map[string]Watcher{
+		config.WatcherInotify: watcher,
+		config.WatcherPoll:    watcher,
+	}, clock)
+}
 
+func newMonitor(cfg *config.Config, log *logger.Logger, watchers map[string]Watcher, clock Clock) (*Monitor, error) {
 	workingDir, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get working directory: %v", err)
@@ -71,52 +154,133 @@ func NewMonitor(cfg *config.Config, log *logger.Logger) (*Monitor, error) {
 		opMax = DefaultMaxConcurrentOperations
 	}
 
+	schedules, err := loadScheduleData(cfg)
+	if err != nil {
+		return nil, err
+	}
+	filePatterns, err := compileFilePatterns(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	opCtx, opCancel := context.WithCancel(context.Background())
 
 	monitor := &Monitor{
-		config:       cfg,
-		logger:       log,
-		watcher:      watcher,
-		watchedDirs:  make(map[string]bool),
-		stopChan:     make(chan struct{}),
-		eventChannel: make(chan model.FileEvent, cfg.Settings.EventChannelBufferSize),
-		workingDir:   workingDir,
-		dedupCache:   make(map[string]time.Time),
-		dirTimers:    make(map[string]*time.Timer),
-		dirBuffers:   make(map[string]map[string]model.FileEvent),
-		dropLog:      make(map[string]time.Time),
-		cleanupStop:  make(chan struct{}),
-		opCtx:        opCtx,
-		opCancel:     opCancel,
-		opSem:        make(chan struct{}, opMax),
+		config:         cfg,
+		logger:         log,
+		watchers:       watchers,
+		clock:          clock,
+		runCommand:     runMonitorCommand,
+		watchBackends:  make(map[string]string),
+		watchDepths:    make(map[string]int),
+		watchedDirs:    make(map[string]bool),
+		stopChan:       make(chan struct{}),
+		eventChannel:   make(chan model.FileEvent, cfg.Settings.EventChannelBufferSize),
+		workingDir:     workingDir,
+		dedupCache:     make(map[string]time.Time),
+		buckets:        make(map[bucketKey]*aggBucket),
+		seqRefs:        make(map[uint64]int),
+		awaiting:       make(map[string]*readinessWait),
+		dropLog:        make(map[string]time.Time),
+		cleanupStop:    make(chan struct{}),
+		opCtx:          opCtx,
+		opCancel:       opCancel,
+		opSem:          make(chan struct{}, opMax),
+		running:        make(map[uint64]runningExecution),
+		history:        openHistory(cfg, log),
+		ledger:         openLedger(cfg, log),
+		journal:        openJournal(cfg, log),
+		spillSignal:    make(chan struct{}, 1),
+		pending:        make(map[string]PendingItem),
+		pendingPath:    PendingFile(cfg),
+		schedules:      schedules,
+		filePatterns:   filePatterns,
+		ignoreMatchers: buildIgnoreMatchers(cfg, log),
+		selfEvents:     make(map[string]time.Time),
 	}
 
+	monitor.loadPending()
+
+	monitor.registerGauges()
+
 	return monitor, nil
 }
 
+// distinctWatchers 返回去重后的监视器列表（注入的监视器可能同时承担多个后端）
+func (m *Monitor) distinctWatchers() []Watcher {
+	list := make([]Watcher, 0, len(m.watchers))
+	for _, name := range []string{config.WatcherInotify, config.WatcherPoll} {
+		w := m.watchers[name]
+		if w == nil {
+			continue
+		}
+		seen := false
+		for _, existing := range list {
+			if existing == w {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
+// currentConfig 返回当前生效的配置（热重载时会被整体替换）
+func (m *Monitor) currentConfig() *config.Config {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.config
+}
+
 func (m *Monitor) Start() error {
 	m.logger.Info("[Monitor] Starting directory monitor")
-	
+
 	// 记录监控配置摘要
 	enabledCount := 0
-	for _, monitor := range m.config.Monitors {
+	cfg := m.currentConfig()
+	for _, monitor := range cfg.Monitors {
 		if monitor.Enabled {
 			enabledCount++
 		}
 	}
-	m.logger.Info("[Monitor] 监控配置摘要 - 总监控项: %d, 启用监控项: %d, 禁用监控项: %d", 
-		len(m.config.Monitors), enabledCount, len(m.config.Monitors)-enabledCount)
+	m.logger.Info("[Monitor] 监控配置摘要 - 总监控项: %d, 启用监控项: %d, 禁用监控项: %d",
+		len(cfg.Monitors), enabledCount, len(cfg.Monitors)-enabledCount)
 
 	if err := m.startWatching(); err != nil {
 		return fmt.Errorf("failed to start watching directories: %v", err)
 	}
+	m.syncCloseWrites(cfg)
+
+	m.pruneHistory(time.Now())
 
+	for _, w := range m.distinctWatchers() {
+		m.wg.Add(1)
+		go m.forwardEvents(w)
+	}
+
+	m.markProcessorProgress()
 	m.wg.Add(1)
 	go m.eventProcessor()
 
+	m.wg.Add(1)
+	go m.scanOnStart()
+
+	if m.journal != nil {
+		m.wg.Add(1)
+		go m.drainJournal()
+	}
+
+	m.schedulePendingFlush()
+
 	m.wg.Add(1)
 	go m.cleanupDaemon()
 
+	m.wg.Add(1)
+	go m.healthDaemon()
+
 	m.logger.Info("[Monitor] Directory monitor started successfully")
 	return nil
 }
@@ -132,16 +296,38 @@ func (m *Monitor) Stop() error {
 
 	close(m.stopChan)
 
+	m.pendingMu.Lock()
+	if m.pendingTimer != nil {
+		m.pendingTimer.Stop()
+		m.pendingTimer = nil
+	}
+	m.pendingMu.Unlock()
+
 	if m.cleanupStop != nil {
 		close(m.cleanupStop)
 	}
 
-	if m.watcher != nil {
-		m.watcher.Stop()
+	for _, w := range m.distinctWatchers() {
+		if err := w.Close(); err != nil {
+			m.logger.Warn("[Monitor] 关闭监视器失败: %v", err)
+		}
 	}
 
 	m.wg.Wait()
 
+	if m.closeWrites != nil {
+		m.closeWrites.close()
+	}
+	if m.history != nil {
+		m.history.Close()
+	}
+	if m.ledger != nil {
+		m.ledger.Close()
+	}
+	if m.journal != nil {
+		m.journal.Close()
+	}
+
 	m.logger.Info("Directory monitor stopped successfully")
 	return nil
 }
@@ -155,34 +341,24 @@ func (m *Monitor) startWatching() error {
 	if m.specificDir != "" {
 		dirsToWatch[m.specificDir] = true
 	} else {
-		for _, monitor := range m.config.Monitors {
+		for _, monitor := range m.currentConfig().Monitors {
 			if monitor.Enabled {
 				dirsToWatch[monitor.Directory] = true
-				monitorInfo[monitor.Directory] = append(monitorInfo[monitor.Directory], 
-					fmt.Sprintf("%s(%s)", monitor.Name, monitor.Command))
+				monitorInfo[monitor.Directory] = append(monitorInfo[monitor.Directory],
+					fmt.Sprintf("%s(%s)", monitor.Name, monitor.CommandLine()))
 			}
 		}
 	}
 
+	cfg := m.currentConfig()
 	watchCount := 0
 	for dir := range dirsToWatch {
-		if err := m.watcher.Watch(dir, func(event model.FileEvent) {
-			select {
-			case m.eventChannel <- event:
-				m.logger.Info("[Monitor] 文件事件已发送到通道: %s", event.Path)
-			default:
-				if m.shouldLogDrop(event.Path) {
-					m.logger.Info("[Monitor] 事件通道已满，丢弃事件: %s", event.Path)
-				}
-			}
-		}); err != nil {
+		if err := m.watchDirectory(dir, m.resolveBackend(cfg, dir), m.resolveDepth(cfg, dir)); err != nil {
 			m.logger.Error("Failed to watch directory %s: %v", dir, err)
 			continue
 		}
-
-		m.watchedDirs[dir] = true
 		watchCount++
-		
+
 		// 记录详细的监控目录信息
 		if monitors, exists := monitorInfo[dir]; exists {
 			m.logger.Info("[Monitor] 开始监控目录: %s, 关联监控项: %v", dir, monitors)
@@ -199,222 +375,580 @@ func (m *Monitor) startWatching() error {
 	return nil
 }
 
+// resolveBackend 选择目录使用的监控后端：任一启用的监控项需要轮询时该目录使用轮询，
+// auto 通过 statfs 识别网络文件系统
+func (m *Monitor) resolveBackend(cfg *config.Config, dir string) string {
+	for _, monitor := range cfg.Monitors {
+		if !monitor.Enabled || monitor.Directory != dir {
+			continue
+		}
+		switch monitor.Watcher {
+		case config.WatcherPoll:
+			return config.WatcherPoll
+		case config.WatcherAuto:
+			fsType, isNet, err := networkFilesystem(dir)
+			if err != nil {
+				m.logger.Warn("[Monitor] 无法识别目录文件系统类型 %s: %v", dir, err)
+				continue
+			}
+			if isNet {
+				m.logger.Info("[Monitor] 目录位于网络文件系统(%s)，使用轮询监控: %s", fsType, dir)
+				return config.WatcherPoll
+			}
+		}
+	}
+	return config.WatcherInotify
+}
+
+// resolveDepth 返回目录需要监控的子目录层数：取该目录上启用的监控项中最深的一个
+func (m *Monitor) resolveDepth(cfg *config.Config, dir string) int {
+	if m.specificDir != "" {
+		return config.UnlimitedDepth
+	}
+	depth := 0
+	for _, monitor := range cfg.Monitors {
+		if !monitor.Enabled || monitor.Directory != dir {
+			continue
+		}
+		d := monitor.WatchDepth()
+		if d == config.UnlimitedDepth {
+			return config.UnlimitedDepth
+		}
+		if d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// watchDirectory 使用指定后端注册目录监控，事件由 forwardEvents 转发到事件通道
+func (m *Monitor) watchDirectory(dir, backend string, depth int) error {
+	if err := m.backendWatcher(backend).Watch(dir, depth); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.watchedDirs[dir] = true
+	m.watchBackends[dir] = backend
+	m.watchDepths[dir] = depth
+	m.mu.Unlock()
+	return nil
+}
+
+// unwatchDirectory 取消目录监控
+func (m *Monitor) unwatchDirectory(dir string) error {
+	m.mu.Lock()
+	backend := m.watchBackends[dir]
+	delete(m.watchedDirs, dir)
+	delete(m.watchBackends, dir)
+	delete(m.watchDepths, dir)
+	m.mu.Unlock()
+
+	return m.backendWatcher(backend).Unwatch(dir)
+}
+
+// backendWatcher 返回后端对应的监视器，未知后端使用 inotify
+func (m *Monitor) backendWatcher(backend string) Watcher {
+	if w, ok := m.watchers[backend]; ok {
+		return w
+	}
+	return m.watchers[config.WatcherInotify]
+}
+
+// watchBackend 返回目录当前使用的监控后端
+func (m *Monitor) watchBackend(dir string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.watchBackends[dir]
+}
+
+// watchDepth 返回目录注册时的子目录层数
+func (m *Monitor) watchDepth(dir string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.watchDepths[dir]
+}
+
+// isWatching 判断目录是否仍在对应后端中注册；监视器不支持查询时视为正常
+func (m *Monitor) isWatching(dir string) bool {
+	if wi, ok := m.backendWatcher(m.watchBackend(dir)).(WatchInspector); ok {
+		return wi.IsWatching(dir)
+	}
+	return true
+}
+
+// rewatchDirectory 在目录原有后端中重新建立监控
+func (m *Monitor) rewatchDirectory(dir string) error {
+	w := m.backendWatcher(m.watchBackend(dir))
+	if wi, ok := w.(WatchInspector); ok {
+		return wi.Rewatch(dir)
+	}
+	return w.Watch(dir, m.watchDepth(dir))
+}
+
+// watchErrorCount 返回所有监视器的累计错误数
+func (m *Monitor) watchErrorCount() uint64 {
+	var total uint64
+	for _, w := range m.distinctWatchers() {
+		if wi, ok := w.(WatchInspector); ok {
+			total += wi.ErrorCount()
+		}
+	}
+	return total
+}
+
+// backendWatchCount 返回后端已注册的监控数量
+func (m *Monitor) backendWatchCount(backend string) int {
+	if wi, ok := m.watchers[backend].(WatchInspector); ok {
+		return wi.WatchCount()
+	}
+	return 0
+}
+
+// forwardEvents 将监视器产生的事件转发到事件处理流程，直到其事件通道关闭
+func (m *Monitor) forwardEvents(w Watcher) {
+	defer m.wg.Done()
+	for event := range w.Events() {
+		m.onWatchEvent(m.watchedRoot(event.Path), event)
+	}
+}
+
+// watchedRoot 返回包含 path 的监控目录（最长匹配），用于指标标签
+func (m *Monitor) watchedRoot(path string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matched := ""
+	for dir := range m.watchedDirs {
+		if len(dir) <= len(matched) {
+			continue
+		}
+		if dir == path || strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			matched = dir
+		}
+	}
+	if matched == "" {
+		return filepath.Dir(path)
+	}
+	return matched
+}
+
+// filterWatchEvent 判断事件是否在进入处理流程前丢弃：文件处置产生的事件、忽略规则文件的变化与被忽略的路径
+func (m *Monitor) filterWatchEvent(event model.FileEvent) bool {
+	log := m.logger.WithFields(eventFields(event)...)
+	switch {
+	case m.isSelfEvent(event.Path):
+		log.Debug("[Monitor] 忽略文件处置产生的事件")
+	case m.isIgnoreFile(event.Path):
+		log.Info("[Monitor] 忽略规则文件已变化，重新加载忽略规则")
+		m.reloadIgnoreRules(m.currentConfig())
+	case m.ignoredByAll(event.Path):
+		log.Debug("[Monitor] 路径匹配忽略规则，忽略事件")
+	default:
+		return false
+	}
+	return true
+}
+
+// onWatchEvent 处理监控后端上报的事件
+func (m *Monitor) onWatchEvent(dir string, event model.FileEvent) {
+	if m.filterWatchEvent(event) {
+		atomic.AddUint64(&m.handledEvents, 1)
+		return
+	}
+	metricEventsReceived.Inc(string(event.Type), dir)
+	m.journalEvent(&event)
+	select {
+	case m.eventChannel <- event:
+		m.logger.WithFields(eventFields(event)...).Info("[Monitor] 文件事件已发送到通道")
+	default:
+		if m.spillEvent(event) {
+			if m.shouldLogDrop(event.Path) {
+				m.logger.WithFields(eventFields(event)...).Info("[Monitor] 事件通道已满，事件暂存在预写日志中")
+			}
+			return
+		}
+		metricEventsDropped.Inc(dropStageMonitor)
+		if m.shouldLogDrop(event.Path) {
+			m.logger.WithFields(eventFields(event)...).Info("[Monitor] 事件通道已满，丢弃事件")
+		}
+	}
+}
+
 func (m *Monitor) eventProcessor() {
 	defer m.wg.Done()
-	
+
 	m.logger.Info("[Monitor] 事件处理器已启动，等待文件事件...")
 	eventCount := 0
 
+	heartbeat := time.NewTicker(ProcessorHeartbeatInterval)
+	defer heartbeat.Stop()
+
 	for {
+		m.markProcessorProgress()
+
 		select {
 		case <-m.stopChan:
 			m.logger.Info("[Monitor] 事件处理器正在停止，处理了 %d 个事件", eventCount)
 			return
+		case <-heartbeat.C:
 		case event := <-m.eventChannel:
 			if atomic.LoadInt32(&m.stopped) == 1 {
 				continue
 			}
 
 			eventCount++
-			m.logger.Info("[Monitor] 事件处理器接收到第 %d 个事件: %s - %s", eventCount, event.Type, event.Path)
+			m.logger.WithFields(eventFields(event)...).Info("[Monitor] 事件处理器接收到第 %d 个事件", eventCount)
 			m.processEvent(event)
 		}
 	}
 }
 
 func (m *Monitor) processEvent(event model.FileEvent) {
-	m.logger.Info("[Monitor] 接收到文件事件: 类型=%s, 路径=%s, 目录=%s", event.Type, event.Path, event.Directory)
+	defer atomic.AddUint64(&m.handledEvents, 1)
 
-	if _, err := os.Stat(event.Path); err != nil {
-		m.logger.Info("[Monitor] 文件未找到，跳过处理: %s", event.Path)
-		return
+	log := m.logger.WithFields(eventFields(event)...)
+	log.Info("[Monitor] 接收到文件事件: 目录=%s", event.Directory)
+
+	// 删除事件对应的文件已不存在，交给配置了 deleted 的监控项处理
+	if event.Type != model.FileDeleted {
+		info, err := os.Stat(event.Path)
+		if err != nil {
+			log.Info("[Monitor] 文件未找到，跳过处理")
+			m.ackEvents(event.Seq)
+			return
+		}
+		// 子目录由监视器加入监控，其中的文件单独上报
+		if info.IsDir() {
+			log.Debug("[Monitor] 目录事件，跳过处理")
+			m.ackEvents(event.Seq)
+			return
+		}
 	}
 
 	m.handleDirectoryAggregation(event)
 }
 
-func (m *Monitor) handleDirectoryAggregation(event model.FileEvent) {
-	m.dirMu.Lock()
-	defer m.dirMu.Unlock()
+// bucketKey 聚合缓冲区的键：监控项与事件所在目录
+type bucketKey struct {
+	monitor string
+	dir     string
+}
+
+// aggBucket 一个监控项在一个目录上的事件缓冲区
+type aggBucket struct {
+	key    bucketKey
+	events map[string]model.FileEvent
+	// seqs 缓冲区中事件（含被同路径新事件覆盖的）的日志序号
+	seqs    []uint64
+	quiet   Timer
+	maxWait Timer
+	// cooling 前沿已触发，静默期结束前的后续事件按 debounce_edge 忽略或缓冲
+	cooling bool
+}
+
+// debounceWindows 返回监控项的静默期与最长等待时间，未设置时使用全局 settings
+func debounceWindows(monitor config.Monitor, settings model.Settings) (quiet, maxWait time.Duration) {
+	quiet = time.Duration(settings.DirectoryStabilityQuietMs) * time.Millisecond
+	if monitor.DebounceSeconds > 0 {
+		quiet = time.Duration(monitor.DebounceSeconds) * time.Second
+	}
+	maxWait = time.Duration(settings.DirectoryStabilityTimeoutSeconds) * time.Second
+	if monitor.MaxWaitSeconds > 0 {
+		maxWait = time.Duration(monitor.MaxWaitSeconds) * time.Second
+	}
+	return quiet, maxWait
+}
+
+func leadingEdge(monitor config.Monitor) bool {
+	return monitor.DebounceEdge == config.DebounceLeading || monitor.DebounceEdge == config.DebounceBoth
+}
 
+// handleDirectoryAggregation 将事件放入每个匹配监控项的缓冲区，各监控项按自己的防抖设置处理
+func (m *Monitor) handleDirectoryAggregation(event model.FileEvent) {
+	cfg := m.currentConfig()
 	dir := filepath.Dir(event.Path)
+	var monitors, ignoring []config.Monitor
+	for _, monitor := range cfg.Monitors {
+		if !monitor.Enabled || !monitor.Covers(event.Path) || !m.selectsFile(monitor, event.Path) {
+			continue
+		}
+		if m.isIgnored(monitor, event.Path) {
+			continue
+		}
+		if monitor.HandlesEvent(event.Type) {
+			monitors = append(monitors, monitor)
+		} else {
+			ignoring = append(ignoring, monitor)
+		}
+	}
 
-	if _, exists := m.dirBuffers[dir]; !exists {
-		m.dirBuffers[dir] = make(map[string]model.FileEvent)
-		m.logger.Info("[Monitor] 初始化目录缓冲区: %s", dir)
+	m.dirMu.Lock()
+	// 文件在静默期内被删除时，从不处理删除事件的监控项缓冲区中移除
+	if event.Type == model.FileDeleted {
+		for _, monitor := range ignoring {
+			m.dropBufferedLocked(bucketKey{monitor: monitorKey(monitor), dir: dir}, event.Path)
+		}
 	}
 
-	m.dirBuffers[dir][event.Path] = event
+	if len(monitors) == 0 {
+		m.dirMu.Unlock()
+		m.logger.WithFields(eventFields(event)...).Debug("[Monitor] 没有匹配的监控项，忽略事件")
+		m.ackEvents(event.Seq)
+		return
+	}
 
-	m.logger.Info("[Monitor] 目录事件聚合 - 目录: %s, 新增文件: %s, 缓冲区文件数: %d",
-		dir, event.Path, len(m.dirBuffers[dir]))
+	if event.Seq != 0 {
+		m.seqRefs[event.Seq] += len(monitors)
+	}
+	var leading []config.Monitor
+	var acks []uint64
+	for _, monitor := range monitors {
+		lead, released := m.aggregateLocked(monitor, dir, event, cfg.Settings)
+		if lead {
+			leading = append(leading, monitor)
+		}
+		acks = append(acks, released...)
+	}
+	m.dirMu.Unlock()
+	m.ackEvents(acks...)
 
-	if timer, exists := m.dirTimers[dir]; exists {
-		timer.Stop()
-		m.logger.Info("[Monitor] 重置目录定时器: %s", dir)
+	// 前沿触发的事件在释放 dirMu 后处理，检查文件时不阻塞事件聚合
+	for _, monitor := range leading {
+		m.processBucketEvents(monitor, dir, []model.FileEvent{event}, []uint64{event.Seq})
 	}
+}
 
-	quietMs := time.Duration(m.config.Settings.DirectoryStabilityQuietMs) * time.Millisecond
-	m.dirTimers[dir] = time.AfterFunc(quietMs, func() {
-		m.logger.Info("[Monitor] 目录静默期结束，开始处理目录事件: %s (静默期: %v)", dir, quietMs)
-		m.processDirectoryEvents(dir)
-	})
+// aggregateLocked 将事件加入监控项在目录上的缓冲区并重置静默期定时器，
+// leading 为 true 表示前沿触发、事件需要立即处理，acks 为释放后需要确认的事件；调用方持有 dirMu
+func (m *Monitor) aggregateLocked(monitor config.Monitor, dir string, event model.FileEvent, settings model.Settings) (leading bool, acks []uint64) {
+	key := bucketKey{monitor: monitorKey(monitor), dir: dir}
+	quiet, maxWait := debounceWindows(monitor, settings)
+	log := m.logger.WithFields(logger.String("monitor_id", key.monitor), logger.String("dir", dir), logger.String("path", event.Path))
+
+	b := m.buckets[key]
+	if b == nil {
+		b = &aggBucket{key: key, events: make(map[string]model.FileEvent)}
+		m.buckets[key] = b
+		if maxWait > 0 && quiet < maxWait {
+			b.maxWait = m.clock.AfterFunc(maxWait, func() {
+				log.Warn("[Monitor] 事件持续到达，达到最长等待时间，强制处理: 最长等待=%v", maxWait)
+				m.flushBucket(b)
+			})
+		}
+		if leadingEdge(monitor) {
+			b.cooling = true
+			b.quiet = m.clock.AfterFunc(quiet, func() { m.flushBucket(b) })
+			log.Info("[Monitor] 前沿触发，立即处理事件: 静默期=%v", quiet)
+			return true, nil
+		}
+	}
 
-	m.logger.Info("[Monitor] 设置目录稳定性定时器: 目录=%s, 静默期=%v, 触发文件=%s",
-		dir, quietMs, event.Path)
+	if b.quiet != nil {
+		b.quiet.Stop()
+	}
+	b.quiet = m.clock.AfterFunc(quiet, func() { m.flushBucket(b) })
 
-	timeoutMs := time.Duration(m.config.Settings.DirectoryStabilityTimeoutSeconds) * time.Second
-	if timeoutMs > 0 && quietMs < timeoutMs {
-		time.AfterFunc(timeoutMs, func() {
-			m.dirMu.Lock()
-			defer m.dirMu.Unlock()
+	if b.cooling && monitor.DebounceEdge == config.DebounceLeading {
+		log.Info("[Monitor] 前沿触发后的静默期内，忽略事件")
+		return false, m.releaseSeqsLocked([]uint64{event.Seq})
+	}
 
-			if events, exists := m.dirBuffers[dir]; exists && len(events) > 0 {
-				m.logger.Warn("[Monitor] 目录稳定性检测超时，强制处理: %s (文件数量: %d, 超时: %v)",
-					dir, len(events), timeoutMs)
-				m.processDirectoryEvents(dir)
-			}
-		})
+	b.events[event.Path] = event
+	if event.Seq != 0 {
+		b.seqs = append(b.seqs, event.Seq)
 	}
+	log.Info("[Monitor] 目录事件聚合: 缓冲区文件数=%d, 静默期=%v", len(b.events), quiet)
+	return false, nil
 }
 
-func (m *Monitor) processDirectoryEvents(dir string) {
-	m.dirMu.Lock()
-	defer m.dirMu.Unlock()
+// dropBufferedLocked 从缓冲区中移除 path 的事件；调用方持有 dirMu
+func (m *Monitor) dropBufferedLocked(key bucketKey, path string) {
+	b := m.buckets[key]
+	if b == nil {
+		return
+	}
+	if _, ok := b.events[path]; !ok {
+		return
+	}
+	delete(b.events, path)
+	m.logger.WithFields(logger.String("monitor_id", key.monitor), logger.String("path", path)).
+		Info("[Monitor] 文件在静默期内被删除，移出缓冲区")
+}
 
-	events := m.dirBuffers[dir]
-	if len(events) == 0 {
+// flushBucket 静默期结束或达到最长等待时间时处理缓冲区中的事件
+func (m *Monitor) flushBucket(b *aggBucket) {
+	m.dirMu.Lock()
+	// 缓冲区已被另一个定时器处理
+	if m.buckets[b.key] != b {
+		m.dirMu.Unlock()
+		return
+	}
+	delete(m.buckets, b.key)
+	if b.quiet != nil {
+		b.quiet.Stop()
+	}
+	if b.maxWait != nil {
+		b.maxWait.Stop()
+	}
+	if len(b.events) == 0 {
+		m.dirMu.Unlock()
 		return
 	}
 
-	m.logger.Info("[Monitor] 目录已稳定，开始处理目录中的文件事件: %s (文件数量: %d)", dir, len(events))
-	
-	// 记录监控目录触发详情
-	fileList := make([]string, 0, len(events))
-	for filePath := range events {
-		fileList = append(fileList, filepath.Base(filePath))
+	var monitor config.Monitor
+	found := false
+	for _, candidate := range m.currentConfig().Monitors {
+		if candidate.Enabled && monitorKey(candidate) == b.key.monitor {
+			monitor, found = candidate, true
+			break
+		}
 	}
-	m.logger.Info("[Monitor] 监控目录触发详情 - 目录: %s, 文件列表: %v, 事件总数: %d", dir, fileList, len(events))
+	if !found {
+		m.logger.WithFields(logger.String("monitor_id", b.key.monitor), logger.String("dir", b.key.dir)).
+			Warn("[Monitor] 监控项已删除或禁用，丢弃缓冲的 %d 个事件", len(b.events))
+		acks := m.releaseSeqsLocked(b.seqs)
+		m.dirMu.Unlock()
+		m.ackEvents(acks...)
+		return
+	}
+	events := sortedEvents(b.events)
+	m.dirMu.Unlock()
 
-	delete(m.dirBuffers, dir)
-	delete(m.dirTimers, dir)
+	m.processBucketEvents(monitor, b.key.dir, events, b.seqs)
+}
 
-	// 收集匹配的监控项，同一监控项只执行一次
-	matchedMonitors := make(map[string]config.Monitor)
-	var firstMatchingEvent model.FileEvent
-	firstEventSet := false
+// processBucketEvents 对监控项执行一次命令（批处理模式下交给全部文件），
+// 未写入完成的文件等待后再处理。检查文件与执行命令时不持有 dirMu，
+// 避免缓慢的文件系统阻塞事件聚合
+func (m *Monitor) processBucketEvents(monitor config.Monitor, dir string, events []model.FileEvent, seqs []uint64) {
+	fileList := make([]string, 0, len(events))
+	for _, event := range events {
+		fileList = append(fileList, filepath.Base(event.Path))
+	}
+	m.logger.Info("[Monitor] 目录已稳定，开始处理: 监控项=%s, 目录=%s, 文件列表=%v", monitorKey(monitor), dir, fileList)
 
-	// 遍历所有事件，收集匹配的监控项
+	// 正在等待写入完成的文件由其等待组处理
+	m.dirMu.Lock()
+	candidates := make([]model.FileEvent, 0, len(events))
 	for _, event := range events {
-		for _, monitor := range m.config.Monitors {
-			if !monitor.Enabled {
-				continue
-			}
+		if m.isAwaiting(monitor, event.Path) {
+			m.logger.WithFields(eventFields(event)...).Debug("[Monitor] 文件正在等待写入完成，跳过")
+			continue
+		}
+		candidates = append(candidates, event)
+	}
+	m.dirMu.Unlock()
+
+	w := m.newReadinessWait(monitor, dir)
+	ready := m.splitByReadiness(monitor, candidates, w)
+	if len(w.events) > 0 {
+		// 日志序号在等待的文件处理后释放
+		w.seqs, seqs = seqs, nil
+		m.dirMu.Lock()
+		m.awaitReadiness(monitor, w)
+		m.dirMu.Unlock()
+	}
+	m.executeReadyEvents(monitor, dir, ready, seqs)
+}
 
-			if event.Directory != monitor.Directory {
+// executeReadyEvents 对已写入完成的文件按属性过滤与调度执行命令，
+// 执行结束后释放事件日志序号的引用；调用方不持有 dirMu
+func (m *Monitor) executeReadyEvents(monitor config.Monitor, dir string, events []model.FileEvent, seqs []uint64) {
+	now := m.clock.Now()
+	active := m.isScheduleActive(monitor)
+	ready := make([]model.FileEvent, 0, len(events))
+	var young []model.FileEvent
+	var youngWait time.Duration
+	for _, event := range events {
+		// 启动扫描生成的事件只交给启用了 scan_on_start 且未处理过该文件的监控项
+		if event.Synthetic {
+			if ok, _ := m.scanAccepts(monitor, event.Path, event.Size, event.ModTime, now); !ok {
 				continue
 			}
-
-			if !m.matchesFilePattern(event.Path, monitor.FilePatterns) {
+		}
+		// trigger 模式的属性过滤作用于批次中的数据文件
+		if event.Type != model.FileDeleted && monitor.Trigger == nil {
+			rejected, wait := m.filterFile(monitor, event.Path, now, true)
+			if rejected {
 				continue
 			}
-
-			if monitor.Schedule != "" && !m.isScheduleActive(monitor.Schedule) {
+			if wait > 0 {
+				young = append(young, event)
+				if youngWait == 0 || wait < youngWait {
+					youngWait = wait
+				}
 				continue
 			}
-
-			// 记录第一个匹配的事件，用于执行命令
-			if !firstEventSet {
-				firstMatchingEvent = event
-				firstEventSet = true
+		}
+		if !active {
+			if monitor.OutsideSchedule == config.OutsideScheduleDefer {
+				m.deferEvent(monitor, event)
 			}
-
-			// 记录匹配的监控项，使用命令作为唯一标识
-			matchedMonitors[monitor.Command] = monitor
+			continue
 		}
+		ready = append(ready, event)
 	}
 
-	// 对每个匹配的监控项执行一次命令
-	for _, monitor := range matchedMonitors {
-		m.logger.Info("[Monitor] 批量处理目录事件，执行命令: %s, 目录: %s, 文件数量: %d", monitor.Command, dir, len(events))
-		m.executeCommand(monitor, firstMatchingEvent)
+	tracker := &ackTracker{}
+	if len(ready) > 0 {
+		m.dispatchReady(monitor, dir, ready, tracker)
 	}
+	if len(young) > 0 {
+		// 日志序号在等待的文件处理后释放
+		m.awaitMinAge(monitor, dir, young, youngWait, seqs)
+		seqs = nil
+	}
+	m.ackWhenDone(tracker, seqs)
 }
 
-
-
-func (m *Monitor) processFileEventInStableDir(event model.FileEvent) {
-	m.logger.Info("[Monitor] 目录已稳定，开始处理文件事件: %s - %s", event.Type, event.Path)
-
-	for _, monitor := range m.config.Monitors {
-		if !monitor.Enabled {
-			m.logger.Info("[Monitor] 监控项已禁用，跳过: %s", monitor.Directory)
-			continue
-		}
-
-		if event.Directory != monitor.Directory {
-			m.logger.Info("[Monitor] 目录不匹配，跳过: 监控目录=%s, 事件目录=%s", monitor.Directory, event.Directory)
-			continue
-		}
-
-		if !m.matchesFilePattern(event.Path, monitor.FilePatterns) {
-			m.logger.Info("[Monitor] 文件模式不匹配，跳过: 文件=%s, 期望模式=%v", event.Path, monitor.FilePatterns)
-			continue
-		}
-
-		if monitor.Schedule != "" {
-			m.logger.Info("[Monitor] 检查调度: 监控项=%s, 调度表达式=%s, 文件=%s",
-				monitor.Command, monitor.Schedule, event.Path)
-			if !m.isScheduleActive(monitor.Schedule) {
-				m.logger.Info("[Monitor] 调度不匹配，跳过监控项: 命令=%s, 调度=%s, 文件=%s",
-					monitor.Command, monitor.Schedule, event.Path)
-				continue
-			} else {
-				m.logger.Info("[Monitor] 调度匹配，继续执行: 命令=%s, 调度=%s, 文件=%s",
-					monitor.Command, monitor.Schedule, event.Path)
-			}
-		} else {
-			m.logger.Info("[Monitor] 无调度限制，继续执行: 命令=%s, 文件=%s",
-				monitor.Command, event.Path)
-		}
-
-		m.logger.Info("[Monitor] 找到匹配的监控项，准备执行命令: 监控名称=%s, 命令=%s, 文件=%s", monitor.Name, monitor.Command, event.Path)
-		m.executeCommand(monitor, event)
+// dispatchReady 按监控项的模式对目录中已就绪的文件执行命令：trigger 模式按触发文件执行，
+// 批处理模式交给全部文件，否则每个目录缓冲区执行一次
+func (m *Monitor) dispatchReady(monitor config.Monitor, dir string, ready []model.FileEvent, tracker *ackTracker) {
+	m.logger.Info("[Monitor] 批量处理目录事件，执行命令: %s, 目录: %s, 匹配文件数量: %d",
+		monitor.CommandLine(), dir, len(ready))
+	switch {
+	case monitor.Trigger != nil:
+		m.executeTriggers(monitor, ready, tracker)
+	case monitor.Batch != nil:
+		m.executeBatch(monitor, ready, tracker)
+	default:
+		m.executeCommand(monitor, ready[0], tracker)
 	}
 }
 
-func (m *Monitor) matchesFilePattern(filePath string, patterns []string) bool {
-	fileName := filepath.Base(filePath)
-	for _, pattern := range patterns {
-		if matched, _ := filepath.Match(pattern, fileName); matched {
-			return true
+// awaitMinAge 文件尚未达到 min_age_seconds 时等待 wait 后再次检查，
+// 期间未达到的文件继续等待，不会被拒绝
+func (m *Monitor) awaitMinAge(monitor config.Monitor, dir string, events []model.FileEvent, wait time.Duration, seqs []uint64) {
+	key := monitorKey(monitor)
+	m.logger.WithFields(logger.String("monitor_id", key), logger.String("dir", dir), logger.Int("file_count", len(events))).
+		Info("[Monitor] 文件尚未达到最短存在时间，等待后再次检查: 间隔=%v", wait)
+	m.clock.AfterFunc(wait, func() {
+		if atomic.LoadInt32(&m.stopped) == 1 {
+			return
 		}
-	}
-	return false
+		for _, candidate := range m.currentConfig().Monitors {
+			if candidate.Enabled && monitorKey(candidate) == key {
+				m.executeReadyEvents(candidate, dir, events, seqs)
+				return
+			}
+		}
+		m.logger.WithFields(logger.String("monitor_id", key), logger.String("dir", dir)).
+			Warn("[Monitor] 监控项已删除或禁用，丢弃等待最短存在时间的 %d 个文件", len(events))
+		m.dirMu.Lock()
+		acks := m.releaseSeqsLocked(seqs)
+		m.dirMu.Unlock()
+		m.ackEvents(acks...)
+	})
 }
 
-func (m *Monitor) isScheduleActive(schedule string) bool {
-	if schedule == "" {
-		m.logger.Debug("[Monitor] 调度检查: 无调度表达式，总是激活")
-		return true
-	}
-
-	gx := gronx.New()
-	now := time.Now().Truncate(time.Minute)
-	due, err := gx.IsDue(schedule, now)
-	if err != nil {
-		m.logger.Error("[Monitor] 调度表达式解析错误: %v, 表达式: %s", err, schedule)
-		return false
-	}
-
-	m.logger.Debug("[Monitor] 调度检查详情: 表达式=%s, 当前时间=%v, 星期=%d, 小时=%d, 分钟=%d, 是否匹配=%v",
-		schedule, now, now.Weekday(), now.Hour(), now.Minute(), due)
-
-	if !due {
-		m.logger.Info("[Monitor] 调度不匹配，跳过执行: 表达式=%s, 当前时间=%v", schedule, now)
+// sortedEvents 将缓冲区中的事件按路径排序，保证执行顺序稳定
+func sortedEvents(events map[string]model.FileEvent) []model.FileEvent {
+	list := make([]model.FileEvent, 0, len(events))
+	for _, event := range events {
+		list = append(list, event)
 	}
-
-	return due
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
 }
 
 func (m *Monitor) isFileStable(filePath string) bool {
@@ -427,8 +961,8 @@ func (m *Monitor) isFileStable(filePath string) bool {
 		m.logger.Debug("[Monitor] 文件大小为0: %s", filePath)
 	}
 
-	minStabilityTime := time.Duration(m.config.Settings.MinStabilityTimeMs) * time.Millisecond
-	fileAge := time.Since(info.ModTime())
+	minStabilityTime := time.Duration(m.currentConfig().Settings.MinStabilityTimeMs) * time.Millisecond
+	fileAge := m.clock.Now().Sub(info.ModTime())
 
 	if fileAge < 0 {
 		m.logger.Debug("[Monitor] 文件修改时间在未来，但仍处理: %s", filePath)
@@ -442,56 +976,170 @@ func (m *Monitor) isFileStable(filePath string) bool {
 	return isStable
 }
 
-func (m *Monitor) executeCommand(monitor config.Monitor, event model.FileEvent) {
-	m.logger.Info("[Monitor] 开始执行命令: %s", monitor.Command)
-	m.logger.Info("[Monitor] 命令执行详情 - 监控名称: %s, 目录: %s, 文件: %s, 事件类型: %s", 
-		monitor.Name, monitor.Directory, event.Path, event.Type)
+func (m *Monitor) executeCommand(monitor config.Monitor, event model.FileEvent, tracker *ackTracker) {
+	log := m.execLogger(monitor, event)
+	log.Info("[Monitor] 开始执行命令 - 监控名称: %s, 目录: %s", monitor.Name, monitor.Directory)
 
-	if m.isDuplicate(monitor.Command, event.Path) {
-		m.logger.Info("[Monitor] 检测到重复执行，跳过: 命令=%s, 文件=%s", monitor.Command, event.Path)
+	if m.isDuplicate(monitor, event.Path) {
+		log.Info("[Monitor] 检测到重复执行，跳过")
 		return
 	}
 
+	executor := m.newExecutor(monitor, event)
+
+	policy := resolveRetryPolicy(m.currentConfig().Settings, monitor)
+
+	m.wg.Add(1)
+	tracker.add()
+	go func() {
+		defer m.wg.Done()
+		log.Debug("[Monitor] 启动命令执行goroutine")
+		tracker.done(m.runWithRetry(monitor, executor, &event, policy))
+	}()
+}
+
+// execLogger 返回附带监控项与触发事件字段的日志记录器
+func (m *Monitor) execLogger(monitor config.Monitor, event model.FileEvent) *logger.Entry {
+	return m.logger.WithFields(append([]logger.Field{
+		logger.String("monitor_id", monitorKey(monitor)),
+		logger.String("command", monitor.CommandLine()),
+	}, eventFields(event)...)...)
+}
+
+// eventFields 文件事件的结构化日志字段
+func eventFields(event model.FileEvent) []logger.Field {
+	return []logger.Field{
+		logger.String("path", event.Path),
+		logger.String("event_type", string(event.Type)),
+	}
+}
+
+// runMonitorCommand 按监控项配置的形式（shell 命令或 exec 参数）执行一次命令
+func runMonitorCommand(ctx context.Context, executor *CommandExecutor, monitor config.Monitor, event *model.FileEvent) (*ExecResult, error) {
+	if len(monitor.Args) > 0 {
+		return executor.ExecuteArgsWithContext(ctx, monitor.Args, event, monitor.Timeout)
+	}
+	return executor.ExecuteCommandWithContext(ctx, monitor.Command, event, monitor.Timeout)
+}
+
+// newExecutor 创建命令执行器并设置监控项与触发事件相关的环境变量
+func (m *Monitor) newExecutor(monitor config.Monitor, event model.FileEvent) *CommandExecutor {
 	executor := NewCommandExecutor(m.logger, monitor.Directory)
+	executor.SetSubstitutionMode(monitor.Substitution)
+	executor.SetLogFields(logger.String("monitor_id", monitorKey(monitor)))
+	executor.SetOutputOptions(newOutputOptions(monitor))
+
+	// 监控项 env 先设置，事件变量同名时优先
+	for k, v := range monitor.Env {
+		executor.SetEnvVar(k, v)
+	}
 
 	executor.SetEnvVar("FILE_PATH", event.Path)
 	executor.SetEnvVar("FILE_NAME", filepath.Base(event.Path))
 	executor.SetEnvVar("FILE_DIR", filepath.Dir(event.Path))
 	executor.SetEnvVar("EVENT_TYPE", string(event.Type))
+	executor.SetEnvVar("OLD_PATH", event.OldPath)
+	return executor
+}
 
-	m.wg.Add(1)
-	go func() {
-		defer m.wg.Done()
-		m.logger.Info("[Monitor] 启动命令执行goroutine: 命令=%s", monitor.Command)
+// runWithRetry 按重试策略执行命令。每次执行前获取操作信号量，执行后立即释放，
+// 等待重试期间不占用并发名额；opCtx 取消时立即放弃后续重试。
+// 返回 false 表示执行被取消。
+func (m *Monitor) runWithRetry(monitor config.Monitor, executor *CommandExecutor, event *model.FileEvent, policy retryPolicy) bool {
+	completed, err := m.executeAttempts(monitor, executor, event, policy)
+	if !completed {
+		// 被取消的执行不做文件处置，文件保持原样
+		return false
+	}
 
+	if err == nil {
+		m.recordProcessed(monitor, executor.paths(event))
+	}
+	m.applyDisposition(monitor, executor.paths(event), err)
+	return true
+}
+
+// executeAttempts 执行命令直到成功、不再重试或被取消。
+// completed 为 false 表示因 opCtx 取消而中止；err 为最后一次执行的错误。
+func (m *Monitor) executeAttempts(monitor config.Monitor, executor *CommandExecutor, event *model.FileEvent, policy retryPolicy) (completed bool, err error) {
+	firstStart := time.Now()
+	log := m.execLogger(monitor, *event)
+
+	for attempt := 1; ; attempt++ {
 		select {
 		case m.opSem <- struct{}{}:
-			defer func() { <-m.opSem }()
-			m.logger.Info("[Monitor] 获取到操作信号量: 命令=%s", monitor.Command)
+			log.Debug("[Monitor] 获取到操作信号量")
 		case <-m.opCtx.Done():
-			m.logger.Info("[Monitor] 操作被取消，无法获取信号量: 命令=%s", monitor.Command)
-			return
+			log.Info("[Monitor] 操作被取消，无法获取信号量")
+			return false, err
 		}
 
-		m.logger.Info("[Monitor] 开始执行命令: %s (超时: %d秒)", monitor.Command, monitor.Timeout)
-		if err := executor.ExecuteCommandWithContext(m.opCtx, monitor.Command, &event, monitor.Timeout); err != nil {
-			m.logger.Error("[Monitor] 命令执行失败: %v", err)
-			return
+		attemptLog := log.WithFields(logger.Int("attempt", attempt), logger.Int("max_attempts", policy.maxAttempts))
+		attemptLog.Info("[Monitor] 开始执行命令 (超时: %d秒)", monitor.Timeout)
+		monitorID := monitorKey(monitor)
+		metricExecutionsStarted.Inc(monitorID)
+		execStart := time.Now()
+		execID := m.trackExecution(monitor, event.Path, execStart)
+		var result *ExecResult
+		result, err = m.runCommand(m.opCtx, executor, monitor, event)
+		m.untrackExecution(execID)
+		m.recordHistory(monitor, executor, event, attempt, execStart, result, err)
+		<-m.opSem
+		duration := time.Since(execStart)
+		metricExecutionDuration.Observe(duration.Seconds(), monitorID)
+		metricExecutionsCompleted.Inc(monitorID, executionResult(err))
+
+		resultLog := attemptLog.WithFields(logger.DurationMs("duration_ms", duration))
+		if err == nil {
+			resultLog.WithFields(logger.Int("exit_code", 0)).Info("[Monitor] 命令执行成功")
+			return true, nil
 		}
+		resultLog = resultLog.WithFields(errorFields(err)...).WithFields(outputFields(result)...)
 
-		m.logger.Info("[Monitor] 命令执行成功: %s", monitor.Command)
-	}()
+		if m.opCtx.Err() != nil {
+			resultLog.Info("[Monitor] 操作被取消，停止重试")
+			return false, err
+		}
+
+		retry, reason := policy.shouldRetry(err)
+		if !retry {
+			resultLog.Error("[Monitor] 命令执行失败(%s，不重试)", reason)
+			return true, err
+		}
+		if attempt >= policy.maxAttempts {
+			resultLog.Error("[Monitor] 命令执行失败，已达最大执行次数 %d", policy.maxAttempts)
+			return true, err
+		}
+
+		delay := policy.backoffDelay(attempt)
+		if policy.maxWindow > 0 && time.Since(firstStart)+delay > policy.maxWindow {
+			resultLog.Error("[Monitor] 命令执行失败，超出最大重试窗口 %v", policy.maxWindow)
+			return true, err
+		}
+
+		resultLog.Warn("[Monitor] 命令执行失败(%s)，%v 后重试", reason, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-m.opCtx.Done():
+			timer.Stop()
+			log.Info("[Monitor] 操作被取消，放弃重试")
+			return false, err
+		}
+	}
 }
 
-func (m *Monitor) isDuplicate(command, filePath string) bool {
-	key := command + "|" + filePath
+func (m *Monitor) isDuplicate(monitor config.Monitor, filePath string) bool {
+	key := monitor.CommandLine() + "|" + filePath
 
 	m.dedupMu.Lock()
 	defer m.dedupMu.Unlock()
 
-	now := time.Now()
+	now := m.clock.Now()
 	if lastExec, exists := m.dedupCache[key]; exists {
-		if now.Sub(lastExec) < time.Duration(m.config.Settings.ExecutionDedupIntervalSeconds)*time.Second {
+		if now.Sub(lastExec) < time.Duration(m.currentConfig().Settings.ExecutionDedupIntervalSeconds)*time.Second {
+			metricDedupHits.Inc(monitorKey(monitor))
 			return true
 		}
 	}
@@ -535,7 +1183,7 @@ func (m *Monitor) cleanupDaemon() {
 
 func (m *Monitor) cleanup() {
 	m.dedupMu.Lock()
-	now := time.Now()
+	now := m.clock.Now()
 	for key, lastExec := range m.dedupCache {
 		if now.Sub(lastExec) > DedupCacheExpiration {
 			delete(m.dedupCache, key)
@@ -550,4 +1198,7 @@ func (m *Monitor) cleanup() {
 		}
 	}
 	m.dropMu.Unlock()
+
+	m.cleanupSelfEvents(now)
+	m.pruneHistory(now)
 }
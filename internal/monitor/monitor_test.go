@@ -0,0 +1,1120 @@
+package monitor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/ignore"
+	"dir-monitor-go/internal/ledger"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+// fakeClock 可控时钟：Advance 推进时间并同步执行到期的定时器
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	fn    func()
+	done  bool
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), fn: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := !t.done
+	t.done = true
+	return active
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	kept := c.timers[:0]
+	for _, t := range c.timers {
+		switch {
+		case t.done:
+		case !t.at.After(c.now):
+			t.done = true
+			due = append(due, t)
+		default:
+			kept = append(kept, t)
+		}
+	}
+	c.timers = kept
+	c.mu.Unlock()
+
+	sort.SliceStable(due, func(i, j int) bool { return due[i].at.Before(due[j].at) })
+	for _, t := range due {
+		t.fn()
+	}
+}
+
+// execRecord 一次被替换的命令执行
+type execRecord struct {
+	monitor string
+	paths   []string
+	// trigger trigger 模式的触发文件
+	trigger string
+}
+
+type monitorHarness struct {
+	t       *testing.T
+	dir     string
+	m       *Monitor
+	watcher *FakeWatcher
+	clock   *fakeClock
+	execs   chan execRecord
+	stopped bool
+}
+
+const testQuiet = time.Second
+
+// newMonitorHarness 使用 FakeWatcher 与可控时钟启动 Monitor；监控项 Directory 为空时使用临时目录
+func newMonitorHarness(t *testing.T, now time.Time, monitors ...config.Monitor) *monitorHarness {
+	t.Helper()
+	return newMonitorHarnessIn(t, t.TempDir(), t.TempDir(), now, nil, monitors...)
+}
+
+// newMonitorHarnessIn 同 newMonitorHarness，使用指定的监控目录与 data_dir；configure 可调整配置
+func newMonitorHarnessIn(t *testing.T, dir, dataDir string, now time.Time, configure func(*config.Config), monitors ...config.Monitor) *monitorHarness {
+	t.Helper()
+	for i := range monitors {
+		if monitors[i].Directory == "" {
+			monitors[i].Directory = dir
+		}
+		monitors[i].Enabled = true
+		monitors[i].Timeout = 10
+	}
+	cfg := &config.Config{
+		Monitors: monitors,
+		Settings: model.Settings{
+			EventChannelBufferSize:        100,
+			DirectoryStabilityQuietMs:     int(testQuiet / time.Millisecond),
+			ExecutionDedupIntervalSeconds: 60,
+			MaxConcurrentOperations:       2,
+			DataDir:                       dataDir,
+		},
+	}
+
+	if configure != nil {
+		configure(cfg)
+	}
+
+	h := &monitorHarness{
+		t:       t,
+		dir:     dir,
+		watcher: NewFakeWatcher(),
+		clock:   newFakeClock(now),
+		execs:   make(chan execRecord, 100),
+	}
+	m, err := NewMonitorWithWatcher(cfg, logger.NewLogger(logger.ERROR, io.Discard), h.watcher, h.clock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.runCommand = func(ctx context.Context, executor *CommandExecutor, monitor config.Monitor, event *model.FileEvent) (*ExecResult, error) {
+		h.execs <- execRecord{monitor: monitor.Name, paths: executor.paths(event), trigger: executor.envVars["TRIGGER_FILE"]}
+		return &ExecResult{ExitCode: 0}, nil
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.stop)
+	h.m = m
+	return h
+}
+
+// stop 停止 Monitor（可重复调用）
+func (h *monitorHarness) stop() {
+	if !h.stopped {
+		h.stopped = true
+		h.m.Stop()
+	}
+}
+
+// emit 创建文件并通过 FakeWatcher 发送事件，等待事件处理器处理完该事件
+func (h *monitorHarness) emit(name string) string {
+	h.t.Helper()
+	path := filepath.Join(h.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		h.t.Fatal(err)
+	}
+	h.emitEvent(model.FileEvent{Type: model.FileCreated, Path: path})
+	return path
+}
+
+// emitEvent 通过 FakeWatcher 发送事件，等待事件处理器处理完该事件
+func (h *monitorHarness) emitEvent(event model.FileEvent) {
+	h.t.Helper()
+	handled := atomic.LoadUint64(&h.m.handledEvents)
+	if err := h.watcher.Emit(event); err != nil {
+		h.t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadUint64(&h.m.handledEvents) == handled {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("event for %s was not handled", event.Path)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitBuffered 等待 path 的事件进入目录缓冲区
+func (h *monitorHarness) waitBuffered(path string) {
+	h.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		buffered := false
+		h.m.dirMu.Lock()
+		for key, b := range h.m.buckets {
+			if _, ok := b.events[path]; ok && key.dir == h.dir {
+				buffered = true
+			}
+		}
+		h.m.dirMu.Unlock()
+		if buffered {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	h.t.Fatalf("event for %s was not buffered", path)
+}
+
+// collect 返回已执行的命令，直到一段时间内没有新的执行
+func (h *monitorHarness) collect() []execRecord {
+	var records []execRecord
+	for {
+		select {
+		case r := <-h.execs:
+			records = append(records, r)
+		case <-time.After(100 * time.Millisecond):
+			sort.Slice(records, func(i, j int) bool { return records[i].monitor < records[j].monitor })
+			return records
+		}
+	}
+}
+
+var testNow = time.Date(2026, 3, 4, 10, 30, 0, 0, time.Local) // 周三
+
+func TestMonitorAggregation(t *testing.T) {
+	tests := []struct {
+		name      string
+		batch     *config.BatchConfig
+		files     []string
+		wantPaths [][]string
+	}{
+		{"single file", nil, []string{"a.csv"}, [][]string{{"a.csv"}}},
+		{"one execution per flush", nil, []string{"b.csv", "a.csv"}, [][]string{{"a.csv"}}},
+		{"batch file list", &config.BatchConfig{Mode: config.BatchModeFileList}, []string{"b.csv", "a.csv"}, [][]string{{"a.csv", "b.csv"}}},
+		{"batch per file", &config.BatchConfig{Mode: config.BatchModePerFile}, []string{"b.csv", "a.csv"}, [][]string{{"a.csv"}, {"b.csv"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*.csv"}, Batch: tt.batch})
+			for _, f := range tt.files {
+				h.emit(f)
+			}
+
+			// 静默期未结束时不执行
+			h.clock.Advance(testQuiet / 2)
+			if got := h.collect(); len(got) != 0 {
+				t.Fatalf("executed before quiet period elapsed: %v", got)
+			}
+
+			h.clock.Advance(testQuiet)
+			got := h.collect()
+			var paths [][]string
+			for _, r := range got {
+				names := make([]string, len(r.paths))
+				for i, p := range r.paths {
+					names[i] = filepath.Base(p)
+				}
+				paths = append(paths, names)
+			}
+			sort.Slice(paths, func(i, j int) bool { return paths[i][0] < paths[j][0] })
+			if !equalNested(paths, tt.wantPaths) {
+				t.Errorf("executions = %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestMonitorBatchMissingFile(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*.csv"},
+		Batch: &config.BatchConfig{Mode: config.BatchModeFileList}})
+	a := h.emit("a.csv")
+	b := h.emit("b.csv")
+	// 文件在静默期后、执行前消失（未收到删除事件）
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	h.clock.Advance(testQuiet)
+
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{b}) {
+		t.Fatalf("records = %+v, want batch [%s]", records, b)
+	}
+}
+
+func TestMonitorQuietPeriodResets(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"},
+		Batch: &config.BatchConfig{Mode: config.BatchModeFileList}})
+
+	h.emit("a.txt")
+	h.clock.Advance(testQuiet * 3 / 4)
+	h.emit("b.txt")
+	h.clock.Advance(testQuiet * 3 / 4)
+	if got := h.collect(); len(got) != 0 {
+		t.Fatalf("executed before directory became quiet: %v", got)
+	}
+	h.clock.Advance(testQuiet)
+	if got := h.collect(); len(got) != 1 || len(got[0].paths) != 2 {
+		t.Fatalf("want one execution with both files, got %v", got)
+	}
+}
+
+func TestMonitorPerMonitorDebounce(t *testing.T) {
+	h := newMonitorHarness(t, testNow,
+		config.Monitor{Name: "fast", Command: "echo fast", FilePatterns: []string{"*"}},
+		config.Monitor{Name: "slow", Command: "echo slow", FilePatterns: []string{"*"}, DebounceSeconds: 5},
+	)
+	h.emit("a.txt")
+	h.clock.Advance(testQuiet)
+	if got := h.collect(); len(got) != 1 || got[0].monitor != "fast" {
+		t.Fatalf("after global quiet period: %v", got)
+	}
+	h.clock.Advance(5 * time.Second)
+	if got := h.collect(); len(got) != 1 || got[0].monitor != "slow" {
+		t.Fatalf("after monitor debounce: %v", got)
+	}
+}
+
+func TestMonitorMaxWait(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"},
+		DebounceSeconds: 2, MaxWaitSeconds: 5, Batch: &config.BatchConfig{Mode: config.BatchModeFileList}})
+
+	// 每 1.5s 到达一个事件，2s 的静默期一直未结束
+	for i, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		if i > 0 {
+			h.clock.Advance(1500 * time.Millisecond)
+		}
+		h.emit(name)
+	}
+	h.clock.Advance(400 * time.Millisecond)
+	if got := h.collect(); len(got) != 0 {
+		t.Fatalf("executed before max wait: %v", got)
+	}
+	h.clock.Advance(200 * time.Millisecond)
+	if got := h.collect(); len(got) != 1 || len(got[0].paths) != 4 {
+		t.Fatalf("want one forced execution with all files, got %v", got)
+	}
+}
+
+func TestMonitorDebounceEdge(t *testing.T) {
+	tests := []struct {
+		edge        string
+		immediate   int
+		afterQuiet  int
+		trailingLen int
+	}{
+		{config.DebounceTrailing, 0, 1, 2},
+		{config.DebounceLeading, 1, 0, 0},
+		{config.DebounceBoth, 1, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.edge, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"},
+				DebounceEdge: tt.edge, Batch: &config.BatchConfig{Mode: config.BatchModeFileList}})
+			h.emit("a.txt")
+			h.clock.Advance(testQuiet / 2)
+			h.emit("b.txt")
+			if got := h.collect(); len(got) != tt.immediate {
+				t.Fatalf("immediate executions = %v, want %d", got, tt.immediate)
+			}
+			h.clock.Advance(testQuiet)
+			got := h.collect()
+			if len(got) != tt.afterQuiet {
+				t.Fatalf("executions after quiet period = %v, want %d", got, tt.afterQuiet)
+			}
+			if len(got) == 1 && len(got[0].paths) != tt.trailingLen {
+				t.Errorf("trailing execution paths = %v, want %d", got[0].paths, tt.trailingLen)
+			}
+
+			// 静默期结束后的下一个事件重新前沿触发
+			h.emit("c.txt")
+			if got := h.collect(); len(got) != tt.immediate {
+				t.Errorf("executions for next burst = %v, want %d", got, tt.immediate)
+			}
+		})
+	}
+}
+
+func TestMonitorPatternMatching(t *testing.T) {
+	tests := []struct {
+		name        string
+		patterns    []string
+		file        string
+		want        bool
+		insensitive bool
+	}{
+		{"extension", []string{"*.csv"}, "data.csv", true, false},
+		{"other extension", []string{"*.csv"}, "data.txt", false, false},
+		{"second pattern", []string{"*.csv", "*.xlsx"}, "data.xlsx", true, false},
+		{"prefix", []string{"report_*"}, "report_2026.pdf", true, false},
+		{"case sensitive", []string{"*.csv"}, "DATA.CSV", false, false},
+		{"single char", []string{"file?.log"}, "file1.log", true, false},
+		{"no patterns", nil, "data.csv", false, false},
+		{"case insensitive", []string{"*.csv"}, "DATA.CSV", true, true},
+		{"exclusion", []string{"*.csv", "!*_partial.csv"}, "data_partial.csv", false, false},
+		{"regexp", []string{`re:^data_\d+\.csv$`}, "data_42.csv", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{
+				Name: "m", Command: "true", FilePatterns: tt.patterns, CaseInsensitive: tt.insensitive,
+			})
+			h.emit(tt.file)
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorEventFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		eventType model.FileEventType
+		exists    bool
+		want      bool
+	}{
+		{"default created", nil, model.FileCreated, true, true},
+		{"default modified", nil, model.FileModified, true, true},
+		{"default ignores deleted", nil, model.FileDeleted, false, false},
+		{"created only", []string{"created", "renamed"}, model.FileModified, true, false},
+		{"renamed", []string{"created", "renamed"}, model.FileRenamed, true, true},
+		{"deleted", []string{"deleted"}, model.FileDeleted, false, true},
+		{"deleted only", []string{"deleted"}, model.FileCreated, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}, Events: tt.events})
+			path := filepath.Join(h.dir, "in.txt")
+			if tt.exists {
+				if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h.emitEvent(model.FileEvent{Type: tt.eventType, Path: path})
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorDeletedWhileBuffered(t *testing.T) {
+	h := newMonitorHarness(t, testNow,
+		config.Monitor{Name: "arrivals", Command: "echo arrivals", FilePatterns: []string{"*"}},
+		config.Monitor{Name: "removals", Command: "echo removals", FilePatterns: []string{"*"}, Events: []string{"deleted"}},
+	)
+	path := h.emit("a.txt")
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	h.emitEvent(model.FileEvent{Type: model.FileDeleted, Path: path})
+	h.clock.Advance(testQuiet)
+
+	got := h.collect()
+	if len(got) != 1 || got[0].monitor != "removals" {
+		t.Fatalf("executions = %v, want only the deletion monitor", got)
+	}
+}
+
+func TestMonitorIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		global []string
+		rules  []string
+		file   string
+		lines  string
+		want   bool
+	}{
+		{"default ignores lock", nil, nil, "ready.lock", "", false},
+		{"default keeps csv", nil, nil, "data.csv", "", true},
+		{"monitor re-includes lock", nil, []string{"!*.lock"}, "ready.lock", "", true},
+		{"monitor adds filepart", nil, []string{"*.filepart"}, "data.csv.filepart", "", false},
+		{"empty global clears defaults", []string{}, nil, ".hidden", "", true},
+		{"ignore file", nil, nil, "data.part", "*.part\n", false},
+		{"ignore file negation", nil, nil, "ready.lock", "!ready.lock\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.lines != "" {
+				if err := os.WriteFile(filepath.Join(dir, ignore.FileName), []byte(tt.lines), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			h := newMonitorHarnessIn(t, dir, t.TempDir(), testNow, func(cfg *config.Config) { cfg.Settings.Ignore = tt.global },
+				config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}, Ignore: tt.rules})
+			h.emit(tt.file)
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorIgnoreFileReload(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}})
+	ignoreFile := filepath.Join(h.dir, ignore.FileName)
+	if err := os.WriteFile(ignoreFile, []byte("*.part\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.emitEvent(model.FileEvent{Type: model.FileCreated, Path: ignoreFile})
+
+	h.emit("a.part")
+	h.emit("b.csv")
+	h.clock.Advance(testQuiet)
+	if got := h.collect(); len(got) != 1 || filepath.Base(got[0].paths[0]) != "b.csv" {
+		t.Fatalf("executions = %v, want only b.csv", got)
+	}
+}
+
+func TestMonitorRecursive(t *testing.T) {
+	tests := []struct {
+		name      string
+		recursive bool
+		maxDepth  int
+		patterns  []string
+		file      string
+		wantDepth int
+		want      bool
+	}{
+		{"top level", false, 0, []string{"*.csv"}, "a.csv", 0, true},
+		{"subdirectory not recursive", false, 0, []string{"*.csv"}, "sub/a.csv", 0, false},
+		{"recursive unlimited", true, 0, []string{"*.csv"}, "sub/deep/a.csv", config.UnlimitedDepth, true},
+		{"within max depth", true, 1, []string{"*.csv"}, "sub/a.csv", 1, true},
+		{"beyond max depth", true, 1, []string{"*.csv"}, "sub/deep/a.csv", 1, false},
+		{"relative pattern", true, 0, []string{"incoming/**/*.csv"}, "incoming/a.csv", config.UnlimitedDepth, true},
+		{"relative pattern nested", true, 0, []string{"incoming/**/*.csv"}, "incoming/x/y/a.csv", config.UnlimitedDepth, true},
+		{"relative pattern other dir", true, 0, []string{"incoming/**/*.csv"}, "other/a.csv", config.UnlimitedDepth, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{
+				Name: "m", Command: "true", FilePatterns: tt.patterns, Recursive: tt.recursive, MaxDepth: tt.maxDepth,
+			})
+			if depth, _ := h.watcher.Depth(h.dir); depth != tt.wantDepth {
+				t.Errorf("watch depth = %d, want %d", depth, tt.wantDepth)
+			}
+			h.emit(tt.file)
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorSkipsDispositionDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"quarantine/bad.csv", "done/old.csv", "new.csv"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := newMonitorHarnessIn(t, dir, t.TempDir(), testNow, nil, config.Monitor{
+		Name: "m", Command: "true", FilePatterns: []string{"*.csv"}, Recursive: true,
+		Batch:       &config.BatchConfig{Mode: config.BatchModeFileList},
+		ScanOnStart: &config.ScanOnStartConfig{},
+		OnSuccess:   &config.DispositionConfig{Action: config.DispositionMove, Target: "done"},
+		OnFailure:   &config.DispositionConfig{Action: config.DispositionQuarantine},
+	})
+	newFile := filepath.Join(dir, "new.csv")
+	h.waitBuffered(newFile)
+	h.emit("done/moved.csv")
+	h.clock.Advance(testQuiet)
+
+	// 递归监控时处置目标目录中的文件既不被启动扫描发现，也不触发命令
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{newFile}) {
+		t.Fatalf("records = %+v, want only %s", records, newFile)
+	}
+}
+
+func TestMonitorAttributeFilters(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{
+		Name: "m", Command: "true", FilePatterns: []string{"*.csv"}, Filters: &config.FilterConfig{MinSize: 1},
+	})
+	empty := filepath.Join(h.dir, "placeholder.csv")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	h.emitEvent(model.FileEvent{Type: model.FileCreated, Path: empty})
+	data := h.emit("data.csv")
+	h.clock.Advance(testQuiet)
+
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{data}) {
+		t.Fatalf("records = %+v, want only %s", records, data)
+	}
+}
+
+func TestMonitorMinAgeLongerThanDebounce(t *testing.T) {
+	const minAge = 5 * time.Second
+	h := newMonitorHarness(t, testNow, config.Monitor{
+		Name: "m", Command: "true", FilePatterns: []string{"*.csv"}, Filters: &config.FilterConfig{MinAgeSeconds: 5},
+	})
+	path := h.emit("data.csv")
+	if err := os.Chtimes(path, testNow, testNow); err != nil {
+		t.Fatal(err)
+	}
+
+	// 静默期结束时文件只存在了 1 秒，等待而不是拒绝
+	h.clock.Advance(testQuiet)
+	if records := h.collect(); len(records) != 0 {
+		t.Fatalf("records = %+v, want none before min_age", records)
+	}
+
+	h.clock.Advance(minAge - testQuiet)
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{path}) {
+		t.Fatalf("records = %+v, want %s once min_age has passed", records, path)
+	}
+}
+
+func TestMonitorReadiness(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	newHarness := func(t *testing.T, rc config.ReadinessConfig) *monitorHarness {
+		rc.IntervalMs = int(interval / time.Millisecond)
+		return newMonitorHarness(t, testNow, config.Monitor{
+			Name: "m", Command: "true", FilePatterns: []string{"*"}, Readiness: &rc,
+		})
+	}
+	expect := func(t *testing.T, h *monitorHarness, want ...string) {
+		t.Helper()
+		var got []string
+		for _, r := range h.collect() {
+			got = append(got, r.paths...)
+		}
+		if !equalStrings(got, want) {
+			t.Fatalf("executed %v, want %v", got, want)
+		}
+	}
+
+	t.Run("size_stable", func(t *testing.T) {
+		h := newHarness(t, config.ReadinessConfig{Strategy: config.ReadinessSizeStable, Checks: 2})
+		path := h.emit("upload.bin")
+		h.clock.Advance(testQuiet)
+		h.clock.Advance(interval)
+		expect(t, h)
+		// 写入继续，重新计数
+		if err := os.WriteFile(path, []byte("more data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		h.clock.Advance(interval)
+		h.clock.Advance(interval)
+		expect(t, h)
+		h.clock.Advance(interval)
+		expect(t, h, path)
+	})
+
+	t.Run("marker", func(t *testing.T) {
+		h := newHarness(t, config.ReadinessConfig{Strategy: config.ReadinessMarker})
+		path := h.emit("data.csv")
+		h.clock.Advance(testQuiet)
+		expect(t, h)
+		h.emit("data.csv.done")
+		h.clock.Advance(interval)
+		expect(t, h, path)
+		h.clock.Advance(testQuiet)
+		expect(t, h)
+	})
+
+	t.Run("exclusive_open", func(t *testing.T) {
+		h := newHarness(t, config.ReadinessConfig{Strategy: config.ReadinessExclusiveOpen})
+		path := h.emit("locked.dat")
+		// 写入方以写方式打开文件（Linux 不要求写入方加锁）并持有 flock（其他平台）
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+			t.Fatal(err)
+		}
+		h.clock.Advance(testQuiet)
+		expect(t, h)
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		h.clock.Advance(interval)
+		expect(t, h, path)
+	})
+
+	t.Run("close_write", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("close_write tracking requires inotify")
+		}
+		h := newHarness(t, config.ReadinessConfig{Strategy: config.ReadinessCloseWrite})
+		path := filepath.Join(h.dir, "stream.log")
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.WriteString("partial"); err != nil {
+			t.Fatal(err)
+		}
+		waitTracked := func(ready bool) {
+			t.Helper()
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				if got, _ := h.m.closeWrites.ready(path); got == ready {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("close_write tracker did not report ready=%v", ready)
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}
+		waitTracked(false)
+		h.emitEvent(model.FileEvent{Type: model.FileCreated, Path: path})
+		h.clock.Advance(testQuiet)
+		expect(t, h)
+
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		waitTracked(true)
+		h.clock.Advance(interval)
+		expect(t, h, path)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		h := newHarness(t, config.ReadinessConfig{Strategy: config.ReadinessMarker, TimeoutSeconds: 1})
+		h.emit("never.csv")
+		h.clock.Advance(testQuiet)
+		for i := 0; i < 11; i++ {
+			h.clock.Advance(interval)
+		}
+		expect(t, h)
+		h.m.dirMu.Lock()
+		waiting := len(h.m.awaiting)
+		h.m.dirMu.Unlock()
+		if waiting != 0 {
+			t.Errorf("%d files still awaiting after timeout", waiting)
+		}
+	})
+}
+
+func TestMonitorTrigger(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{
+		Name: "m", Command: "true", FilePatterns: []string{"*.csv"},
+		Trigger: &config.TriggerConfig{Patterns: []string{"_SUCCESS", "*.ok"}, Remove: true},
+	})
+	a := h.emit("a.csv")
+	b := h.emit("b.csv")
+	h.emit("notes.txt")
+	h.clock.Advance(testQuiet)
+	if records := h.collect(); len(records) != 0 {
+		t.Fatalf("data files executed without trigger: %+v", records)
+	}
+
+	trigger := h.emit("_SUCCESS")
+	h.clock.Advance(testQuiet)
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{a, b}) || records[0].trigger != trigger {
+		t.Fatalf("records = %+v, want batch [%s %s] triggered by %s", records, a, b, trigger)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for fileExists(trigger) {
+		if time.Now().After(deadline) {
+			t.Fatalf("trigger file %s was not removed", trigger)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// 已处理的数据文件留在目录中，下一次触发只包含新文件
+	c := h.emit("c.csv")
+	h.emit("_SUCCESS")
+	h.clock.Advance(testQuiet)
+	records = h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{c}) {
+		t.Fatalf("records = %+v, want batch [%s]", records, c)
+	}
+}
+
+func TestMonitorSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		now      time.Time
+		want     bool
+	}{
+		{"no schedule", "", testNow, true},
+		{"inside hours", "* 9-17 * * *", testNow, true},
+		{"outside hours", "* 15-21 * * 1-5", testNow, false},
+		{"weekday", "* * * * 1-5", testNow, true},
+		{"weekend only", "* * * * 0,6", testNow, false},
+		{"evening", "* 15-21 * * 1-5", time.Date(2026, 3, 4, 21, 59, 0, 0, time.Local), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, tt.now, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}, Schedule: tt.schedule})
+			h.emit("in.txt")
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorSharedDirectory(t *testing.T) {
+	h := newMonitorHarness(t, testNow,
+		config.Monitor{Name: "csv", Command: "echo csv", FilePatterns: []string{"*.csv"}},
+		config.Monitor{Name: "day", Command: "echo day", FilePatterns: []string{"*.csv"}, Schedule: "* 8-18 * * *"},
+		config.Monitor{Name: "night", Command: "echo night", FilePatterns: []string{"*.csv"}, Schedule: "* 0-6 * * *"},
+		config.Monitor{Name: "txt", Command: "echo txt", FilePatterns: []string{"*.txt"}},
+	)
+	h.emit("a.csv")
+	h.clock.Advance(testQuiet)
+
+	var names []string
+	for _, r := range h.collect() {
+		names = append(names, r.monitor)
+	}
+	if want := []string{"csv", "day"}; !equalStrings(names, want) {
+		t.Errorf("executed monitors = %v, want %v", names, want)
+	}
+}
+
+func TestMonitorDedup(t *testing.T) {
+	tests := []struct {
+		name  string
+		delay time.Duration
+		want  int
+	}{
+		{"within interval", 30 * time.Second, 1},
+		{"after interval", 61 * time.Second, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}})
+			h.emit("a.txt")
+			h.clock.Advance(testQuiet)
+			first := h.collect()
+
+			h.clock.Advance(tt.delay)
+			h.emit("a.txt")
+			h.clock.Advance(testQuiet)
+			if got := len(first) + len(h.collect()); got != tt.want {
+				t.Errorf("executions = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMonitorScanOnStart(t *testing.T) {
+	dir, dataDir := t.TempDir(), t.TempDir()
+	write := func(name string, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := testNow.Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("fresh.csv", time.Minute)
+	write("old.csv", 48*time.Hour)
+	done := write("done.csv", time.Minute)
+	write("other.txt", time.Minute)
+	write("z_last.csv", time.Minute)
+
+	scanning := config.Monitor{Name: "scan", Directory: dir, Command: "echo scan", FilePatterns: []string{"*.csv"},
+		Batch:       &config.BatchConfig{Mode: config.BatchModeFileList},
+		ScanOnStart: &config.ScanOnStartConfig{MaxAgeSeconds: 86400, SkipProcessed: true}}
+	plain := config.Monitor{Name: "plain", Directory: dir, Command: "echo plain", FilePatterns: []string{"*"}}
+
+	// 服务停止前已处理过 done.csv
+	store, err := ledger.Open(filepath.Join(dataDir, LedgerSubdir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, _ := os.Stat(done)
+	store.Add(ledger.Entry{MonitorID: monitorKey(scanning), Path: done, Size: info.Size(), ModTime: info.ModTime()})
+	store.Close()
+
+	h := newMonitorHarnessIn(t, dir, dataDir, testNow, nil, scanning, plain)
+	h.waitBuffered(filepath.Join(dir, "z_last.csv"))
+	h.clock.Advance(testQuiet)
+
+	got := h.collect()
+	if len(got) != 1 || got[0].monitor != "scan" {
+		t.Fatalf("want a single execution of the scanning monitor, got %v", got)
+	}
+	var names []string
+	for _, p := range got[0].paths {
+		names = append(names, filepath.Base(p))
+	}
+	if want := []string{"fresh.csv", "z_last.csv"}; !equalStrings(names, want) {
+		t.Errorf("scanned files = %v, want %v", names, want)
+	}
+
+	// 成功处理后写入记录
+	fresh := filepath.Join(dir, "fresh.csv")
+	info, _ = os.Stat(fresh)
+	if !h.m.ledger.Contains(monitorKey(scanning), fresh, info.Size(), info.ModTime()) {
+		t.Error("processed file should be recorded in the ledger")
+	}
+}
+
+func TestMonitorJournalReplay(t *testing.T) {
+	dir, dataDir := t.TempDir(), t.TempDir()
+	enableJournal := func(cfg *config.Config) { cfg.Settings.JournalEnabled = true }
+	mon := config.Monitor{Name: "m", Directory: dir, Command: "true", FilePatterns: []string{"*.csv"}}
+
+	// 事件已写入日志，但目录稳定前服务停止
+	first := newMonitorHarnessIn(t, dir, dataDir, testNow, enableJournal, mon)
+	path := first.emit("a.csv")
+	first.stop()
+	if got := first.collect(); len(got) != 0 {
+		t.Fatalf("executed before stop: %v", got)
+	}
+
+	second := newMonitorHarnessIn(t, dir, dataDir, testNow, enableJournal, mon)
+	second.waitBuffered(path)
+	second.clock.Advance(testQuiet)
+	if got := second.collect(); len(got) != 1 || got[0].paths[0] != path {
+		t.Fatalf("replayed executions = %v", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for second.m.journal.Pending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("event not acknowledged, pending = %d", second.m.journal.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMonitorDeferOutsideSchedule(t *testing.T) {
+	dir, dataDir := t.TempDir(), t.TempDir()
+	mon := config.Monitor{Name: "m", Directory: dir, Command: "true", FilePatterns: []string{"*.csv"},
+		Schedule: "* 15-21 * * 1-5", OutsideSchedule: config.OutsideScheduleDefer}
+
+	first := newMonitorHarnessIn(t, dir, dataDir, testNow, nil, mon)
+	a := first.emit("a.csv")
+	b := first.emit("b.csv")
+	first.clock.Advance(testQuiet)
+	if got := first.collect(); len(got) != 0 {
+		t.Fatalf("executed outside schedule: %v", got)
+	}
+	first.stop()
+
+	// 延后的事件在重启后仍然保留
+	second := newMonitorHarnessIn(t, dir, dataDir, testNow, nil, mon)
+	items := second.m.PendingItems()
+	wantDue := time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local)
+	if len(items) != 2 || items[0].Path != a || items[1].Path != b || !items[0].Due.Equal(wantDue) {
+		t.Fatalf("pending after restart = %+v", items)
+	}
+
+	second.clock.Advance(wantDue.Sub(testNow))
+	// 与调度窗口内的处理一致，同一目录的延后事件只执行一次
+	if records := second.collect(); len(records) != 1 || !equalStrings(records[0].paths, []string{a}) {
+		t.Fatalf("executed at window open = %+v, want one execution for %s", records, a)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(second.m.PendingItems()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("pending not cleared: %+v", second.m.PendingItems())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if items, err := LoadPending(PendingFile(second.m.currentConfig())); err != nil || len(items) != 0 {
+		t.Errorf("persisted pending = %+v, err = %v", items, err)
+	}
+}
+
+func TestMonitorDeferredBatch(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*.csv"},
+		Schedule: "* 15-21 * * 1-5", OutsideSchedule: config.OutsideScheduleDefer,
+		Batch: &config.BatchConfig{Mode: config.BatchModeFileList}})
+	a := h.emit("a.csv")
+	b := h.emit("b.csv")
+	h.clock.Advance(testQuiet)
+	if got := h.collect(); len(got) != 0 {
+		t.Fatalf("executed outside schedule: %v", got)
+	}
+
+	h.clock.Advance(time.Date(2026, 3, 4, 15, 0, 0, 0, time.Local).Sub(testNow))
+	records := h.collect()
+	if len(records) != 1 || !equalStrings(records[0].paths, []string{a, b}) {
+		t.Fatalf("records = %+v, want one batch with %s and %s", records, a, b)
+	}
+}
+
+func TestMonitorTimezoneAndCalendar(t *testing.T) {
+	if _, err := time.LoadLocation("Asia/Tokyo"); err != nil {
+		t.Skip("zoneinfo not available")
+	}
+	// 10:30 UTC = 19:30 Asia/Tokyo
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	holidays := filepath.Join(t.TempDir(), "holidays.txt")
+	if err := os.WriteFile(holidays, []byte("2026-03-04\n2026-03-05\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withCalendar := func(cfg *config.Config) {
+		cfg.Calendars = map[string]config.CalendarConfig{"holidays": {File: holidays}}
+	}
+
+	tests := []struct {
+		name     string
+		timezone string
+		calendar string
+		want     bool
+	}{
+		{"utc business hours", "UTC", "", true},
+		{"tokyo evening", "Asia/Tokyo", "", false},
+		{"holiday", "UTC", "holidays", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newMonitorHarnessIn(t, t.TempDir(), t.TempDir(), now, withCalendar, config.Monitor{Name: "m", Command: "true",
+				FilePatterns: []string{"*"}, Schedule: "* 9-17 * * *", Timezone: tt.timezone, Calendar: tt.calendar})
+			h.emit("in.txt")
+			h.clock.Advance(testQuiet)
+			if got := len(h.collect()) == 1; got != tt.want {
+				t.Errorf("executed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// 延后到日历允许的下一个调度窗口
+	h := newMonitorHarnessIn(t, t.TempDir(), t.TempDir(), now, withCalendar, config.Monitor{Name: "m", Command: "true",
+		FilePatterns: []string{"*"}, Schedule: "* 9-17 * * *", Timezone: "UTC", Calendar: "holidays",
+		OutsideSchedule: config.OutsideScheduleDefer})
+	h.emit("in.txt")
+	h.clock.Advance(testQuiet)
+	items := h.m.PendingItems()
+	if want := time.Date(2026, 3, 6, 9, 0, 0, 0, time.UTC); len(items) != 1 || !items[0].Due.Equal(want) {
+		t.Fatalf("pending = %+v, want due %v", items, want)
+	}
+}
+
+func TestMonitorJournalAckAfterAllMonitors(t *testing.T) {
+	enableJournal := func(cfg *config.Config) { cfg.Settings.JournalEnabled = true }
+	h := newMonitorHarnessIn(t, t.TempDir(), t.TempDir(), testNow, enableJournal,
+		config.Monitor{Name: "fast", Command: "echo fast", FilePatterns: []string{"*"}},
+		config.Monitor{Name: "slow", Command: "echo slow", FilePatterns: []string{"*"}, DebounceSeconds: 5},
+	)
+	h.emit("a.txt")
+	h.clock.Advance(testQuiet)
+	if got := h.collect(); len(got) != 1 {
+		t.Fatalf("executions = %v", got)
+	}
+	if pending := h.m.journal.Pending(); pending != 1 {
+		t.Fatalf("event acknowledged before every monitor processed it, pending = %d", pending)
+	}
+
+	h.clock.Advance(5 * time.Second)
+	h.collect()
+	deadline := time.Now().Add(2 * time.Second)
+	for h.m.journal.Pending() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("event not acknowledged, pending = %d", h.m.journal.Pending())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestMonitorWatchesThroughInjectedWatcher(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*"}})
+	if got := h.watcher.Watched(); !equalStrings(got, []string{h.dir}) {
+		t.Fatalf("watched = %v, want %v", got, []string{h.dir})
+	}
+	if !h.m.isWatching(h.dir) {
+		t.Error("monitor should report the directory as watched")
+	}
+	if err := h.m.unwatchDirectory(h.dir); err != nil || len(h.watcher.Watched()) != 0 {
+		t.Errorf("unwatch: err=%v watched=%v", err, h.watcher.Watched())
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalNested(a, b [][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalStrings(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// exclusiveOpen 判断是否没有进程以写方式打开文件。先尝试取得读租约（F_SETLEASE F_RDLCK）：
+// 只要有进程以写方式打开文件，内核就拒绝读租约，因此不需要写入方配合加锁，
+// 对 SFTP、scp、rsync 等写入方同样有效。无权取得租约（非文件属主且没有 CAP_LEASE）
+// 或文件系统不支持租约（如 NFS）时改为扫描 /proc/*/fd
+func exclusiveOpen(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	fd := f.Fd()
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETLEASE, syscall.F_RDLCK)
+	switch errno {
+	case 0:
+		_, _, _ = syscall.Syscall(syscall.SYS_FCNTL, fd, syscall.F_SETLEASE, syscall.F_UNLCK)
+		return true
+	case syscall.EAGAIN:
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return !openForWriting(info)
+}
+
+// openForWriting 扫描 /proc/*/fd 查找以写方式打开同一文件的描述符。
+// 无权读取的进程（其他用户的进程，服务未以 root 运行时）会被跳过
+func openForWriting(target os.FileInfo) bool {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return false
+	}
+	self := strconv.Itoa(os.Getpid())
+	for _, proc := range procs {
+		pid := proc.Name()
+		if _, err := strconv.Atoi(pid); err != nil || pid == self {
+			continue
+		}
+		fdDir := filepath.Join("/proc", pid, "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			info, err := os.Stat(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !os.SameFile(info, target) {
+				continue
+			}
+			if fdWritable(filepath.Join("/proc", pid, "fdinfo", fd.Name())) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fdWritable 读取 fdinfo 中的 flags（八进制）判断描述符是否以写方式打开
+func fdWritable(fdinfo string) bool {
+	data, err := os.ReadFile(fdinfo)
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		value, ok := strings.CutPrefix(line, "flags:")
+		if !ok {
+			continue
+		}
+		flags, err := strconv.ParseUint(strings.TrimSpace(value), 8, 32)
+		if err != nil {
+			return false
+		}
+		return flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0
+	}
+	return false
+}
@@ -0,0 +1,316 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+)
+
+const (
+	// 输出文件名中的时间格式
+	OutputFileTimeFormat = "20060102-150405.000"
+	// 逐行写日志时单行的最大长度，超出部分按多行输出
+	OutputMaxLineBytes = 4096
+
+	outputDirPerm  = 0755
+	outputFilePerm = 0644
+)
+
+// unsafePathChars 匹配不能直接用于文件名的字符
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// outputOptions 命令输出处理选项（已填充默认值）
+type outputOptions struct {
+	// dir 非空时输出写入 dir/<monitorID>/<timestamp>-<file>.log
+	dir         string
+	monitorID   string
+	maxBytes    int64
+	tailBytes   int
+	stdoutLevel string
+	stderrLevel string
+}
+
+// newOutputOptions 根据监控项的 output 配置生成输出处理选项
+func newOutputOptions(monitor config.Monitor) outputOptions {
+	opts := outputOptions{
+		monitorID:   monitorKey(monitor),
+		maxBytes:    config.DefaultOutputMaxBytes,
+		tailBytes:   config.DefaultOutputTailBytes,
+		stdoutLevel: config.DefaultOutputLogLevel,
+		stderrLevel: config.DefaultOutputLogLevel,
+	}
+
+	oc := monitor.Output
+	if oc == nil {
+		return opts
+	}
+	if oc.ToFile {
+		opts.dir = oc.Dir
+		if opts.dir == "" {
+			opts.dir = config.DefaultOutputDir
+		}
+	}
+	if oc.MaxBytes > 0 {
+		opts.maxBytes = oc.MaxBytes
+	}
+	if oc.TailBytes > 0 {
+		opts.tailBytes = oc.TailBytes
+	}
+	if oc.StdoutLevel != "" {
+		opts.stdoutLevel = oc.StdoutLevel
+	}
+	if oc.StderrLevel != "" {
+		opts.stderrLevel = oc.StderrLevel
+	}
+	return opts
+}
+
+// commandOutput 一次执行的输出处理：保留末尾片段、按行写日志、可选写入文件
+type commandOutput struct {
+	stdoutTail *tailBuffer
+	stderrTail *tailBuffer
+	stdoutLog  *lineLogWriter
+	stderrLog  *lineLogWriter
+	file       *limitedFile
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// newCommandOutput 创建输出处理；打开输出文件失败时仅告警，仍保留末尾片段与日志输出
+func newCommandOutput(opts outputOptions, fileName string, start time.Time, log *logger.Entry) *commandOutput {
+	out := &commandOutput{
+		stdoutTail: newTailBuffer(opts.tailBytes),
+		stderrTail: newTailBuffer(opts.tailBytes),
+		stdoutLog:  newLineLogWriter(log.WithFields(logger.String("stream", "stdout")), opts.stdoutLevel),
+		stderrLog:  newLineLogWriter(log.WithFields(logger.String("stream", "stderr")), opts.stderrLevel),
+	}
+
+	if opts.dir != "" {
+		file, err := openOutputFile(opts, fileName, start)
+		if err != nil {
+			log.WithFields(logger.Err(err)).Warn("创建命令输出文件失败")
+		} else {
+			out.file = file
+		}
+	}
+
+	stdout := []io.Writer{out.stdoutTail}
+	stderr := []io.Writer{out.stderrTail}
+	if out.stdoutLog != nil {
+		stdout = append(stdout, out.stdoutLog)
+	}
+	if out.stderrLog != nil {
+		stderr = append(stderr, out.stderrLog)
+	}
+	if out.file != nil {
+		stdout = append(stdout, out.file)
+		stderr = append(stderr, out.file)
+	}
+	out.Stdout = io.MultiWriter(stdout...)
+	out.Stderr = io.MultiWriter(stderr...)
+	return out
+}
+
+// Close 输出未换行的剩余内容并关闭输出文件，在进程结束后调用
+func (o *commandOutput) Close() {
+	if o.stdoutLog != nil {
+		o.stdoutLog.Flush()
+	}
+	if o.stderrLog != nil {
+		o.stderrLog.Flush()
+	}
+	if o.file != nil {
+		o.file.Close()
+	}
+}
+
+// Fill 将输出片段与文件信息写入执行结果
+func (o *commandOutput) Fill(result *ExecResult) {
+	result.Stdout = o.stdoutTail.String()
+	result.Stderr = o.stderrTail.String()
+	if o.file != nil {
+		result.OutputFile = o.file.path
+		result.OutputTruncated = o.file.truncated
+	}
+}
+
+// outputFields 失败日志附带的输出字段：输出末尾片段与输出文件路径
+func outputFields(result *ExecResult) []logger.Field {
+	if result == nil {
+		return nil
+	}
+	var fields []logger.Field
+	if result.Stdout != "" {
+		fields = append(fields, logger.String("stdout_tail", result.Stdout))
+	}
+	if result.Stderr != "" {
+		fields = append(fields, logger.String("stderr_tail", result.Stderr))
+	}
+	if result.OutputFile != "" {
+		fields = append(fields, logger.String("output_file", result.OutputFile))
+	}
+	return fields
+}
+
+// openOutputFile 创建 <dir>/<monitorID>/<timestamp>-<file>.log
+func openOutputFile(opts outputOptions, fileName string, start time.Time) (*limitedFile, error) {
+	dir := filepath.Join(opts.dir, sanitizePathComponent(opts.monitorID))
+	if err := os.MkdirAll(dir, outputDirPerm); err != nil {
+		return nil, err
+	}
+	name := start.Format(OutputFileTimeFormat) + "-" + sanitizePathComponent(fileName) + ".log"
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, outputFilePerm)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedFile{f: f, path: path, remaining: opts.maxBytes}, nil
+}
+
+func sanitizePathComponent(s string) string {
+	s = unsafePathChars.ReplaceAllString(s, "_")
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return s
+}
+
+// limitedFile stdout 与 stderr 共用的输出文件，超过上限后丢弃后续输出。
+// 写入错误不会返回给调用方，以免中断命令的输出复制。
+type limitedFile struct {
+	mu        sync.Mutex
+	f         *os.File
+	path      string
+	remaining int64
+	truncated bool
+	failed    bool
+}
+
+func (l *limitedFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.failed {
+		return len(p), nil
+	}
+	data := p
+	if int64(len(data)) > l.remaining {
+		data = data[:l.remaining]
+		l.truncated = true
+	}
+	if len(data) > 0 {
+		if _, err := l.f.Write(data); err != nil {
+			l.failed = true
+		}
+		l.remaining -= int64(len(data))
+	}
+	return len(p), nil
+}
+
+func (l *limitedFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.truncated && !l.failed {
+		fmt.Fprintf(l.f, "\n[output truncated: limit reached]\n")
+	}
+	return l.f.Close()
+}
+
+// tailBuffer 只保留最后 max 字节的写入内容
+type tailBuffer struct {
+	max     int
+	buf     []byte
+	dropped int64
+}
+
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	if t.max <= 0 {
+		t.dropped += int64(len(p))
+		return len(p), nil
+	}
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.dropped += int64(over)
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// String 返回保留的内容，有丢弃时带截断提示，且不从多字节字符中间开始
+func (t *tailBuffer) String() string {
+	start := 0
+	for start < len(t.buf) && t.dropped > 0 && !utf8.RuneStart(t.buf[start]) {
+		start++
+	}
+	if t.dropped == 0 {
+		return string(t.buf)
+	}
+	return fmt.Sprintf("...(truncated %d bytes)\n", t.dropped+int64(start)) + string(t.buf[start:])
+}
+
+// lineLogWriter 将写入内容按行输出到日志
+type lineLogWriter struct {
+	log   *logger.Entry
+	level logger.LogLevel
+	buf   []byte
+}
+
+// newLineLogWriter 级别为 none 时返回 nil
+func newLineLogWriter(log *logger.Entry, level string) *lineLogWriter {
+	if level == config.OutputLevelNone {
+		return nil
+	}
+	lvl, err := logger.ParseLevel(level)
+	if err != nil {
+		lvl = logger.DEBUG
+	}
+	return &lineLogWriter{log: log, level: lvl}
+}
+
+func (w *lineLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	rest := w.buf
+	for {
+		i := bytes.IndexByte(rest, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(rest[:i])
+		rest = rest[i+1:]
+	}
+	for len(rest) >= OutputMaxLineBytes {
+		w.emit(rest[:OutputMaxLineBytes])
+		rest = rest[OutputMaxLineBytes:]
+	}
+	w.buf = append(w.buf[:0], rest...)
+	return len(p), nil
+}
+
+// Flush 输出最后一行未换行的内容
+func (w *lineLogWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *lineLogWriter) emit(line []byte) {
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	w.log.Log(w.level, "Command output: %s", line)
+}
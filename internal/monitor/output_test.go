@@ -0,0 +1,79 @@
+package monitor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/logger"
+)
+
+func TestTailBuffer(t *testing.T) {
+	tb := newTailBuffer(8)
+	tb.Write([]byte("hello "))
+	tb.Write([]byte("world"))
+	if got := tb.String(); got != "...(truncated 3 bytes)\nlo world" {
+		t.Errorf("got %q", got)
+	}
+
+	small := newTailBuffer(8)
+	small.Write([]byte("ok"))
+	if got := small.String(); got != "ok" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLineLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.NewLogger(logger.DEBUG, &buf)
+	w := newLineLogWriter(log.WithFields(logger.String("stream", "stderr")), "warn")
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\r\nthird"))
+	w.Flush()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{"first", "second", "third"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines: %q", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.Contains(line, "WARN Command output: "+want[i]+" stream=stderr") {
+			t.Errorf("line %d = %q", i, line)
+		}
+	}
+
+	if newLineLogWriter(log.WithFields(), "none") != nil {
+		t.Error("level none should disable line logging")
+	}
+}
+
+func TestCommandOutputFile(t *testing.T) {
+	dir := t.TempDir()
+	opts := outputOptions{dir: dir, monitorID: "a/b", maxBytes: 5, tailBytes: 64, stdoutLevel: "none", stderrLevel: "none"}
+	log := logger.NewLogger(logger.ERROR, &bytes.Buffer{})
+
+	out := newCommandOutput(opts, "in put.txt", time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local), log.WithFields())
+	out.Stdout.Write([]byte("abc"))
+	out.Stderr.Write([]byte("defgh"))
+	out.Close()
+
+	var result ExecResult
+	out.Fill(&result)
+	if want := filepath.Join(dir, "a_b", "20260102-030405.000-in_put.txt.log"); result.OutputFile != want {
+		t.Errorf("output file = %q, want %q", result.OutputFile, want)
+	}
+	if !result.OutputTruncated || result.Stdout != "abc" || result.Stderr != "defgh" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	data, err := os.ReadFile(result.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "abcde\n[output truncated") {
+		t.Errorf("file content = %q", data)
+	}
+}
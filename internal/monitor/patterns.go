@@ -0,0 +1,56 @@
+package monitor
+
+import (
+	"fmt"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/pattern"
+)
+
+// monitorPatterns 监控项编译后的文件模式与触发文件模式
+type monitorPatterns struct {
+	files   *pattern.Set
+	trigger *pattern.Set
+}
+
+// compileFilePatterns 按监控项编译文件模式
+func compileFilePatterns(cfg *config.Config) (map[string]monitorPatterns, error) {
+	sets := make(map[string]monitorPatterns, len(cfg.Monitors))
+	for _, monitor := range cfg.Monitors {
+		files, err := monitor.CompilePatterns()
+		if err != nil {
+			return nil, fmt.Errorf("invalid file patterns for monitor %s: %v", monitor.Directory, err)
+		}
+		trigger, err := monitor.CompileTriggerPatterns()
+		if err != nil {
+			return nil, fmt.Errorf("invalid trigger patterns for monitor %s: %v", monitor.Directory, err)
+		}
+		sets[monitorKey(monitor)] = monitorPatterns{files: files, trigger: trigger}
+	}
+	return sets, nil
+}
+
+// patternsFor 返回监控项当前的文件模式
+func (m *Monitor) patternsFor(monitor config.Monitor) monitorPatterns {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.filePatterns[monitorKey(monitor)]
+}
+
+// matchesFilePattern 判断文件相对于监控目录的路径是否匹配监控项的文件模式
+func (m *Monitor) matchesFilePattern(monitor config.Monitor, filePath string) bool {
+	rel, ok := monitor.RelPath(filePath)
+	if !ok {
+		return false
+	}
+	return m.patternsFor(monitor).files.Match(rel)
+}
+
+// isTriggerFile 判断文件是否匹配监控项的触发文件模式；未配置 trigger 时总是 false
+func (m *Monitor) isTriggerFile(monitor config.Monitor, filePath string) bool {
+	rel, ok := monitor.RelPath(filePath)
+	if !ok {
+		return false
+	}
+	return m.patternsFor(monitor).trigger.Match(rel)
+}
@@ -0,0 +1,335 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// PendingFileName 延后执行的事件在 data_dir 下的持久化文件
+	PendingFileName = "pending.json"
+)
+
+// PendingItem 因不在调度时间内而延后执行的文件事件
+type PendingItem struct {
+	MonitorID string    `json:"monitor_id"`
+	Path      string    `json:"path"`
+	OldPath   string    `json:"old_path,omitempty"`
+	EventType string    `json:"event_type"`
+	Directory string    `json:"directory"`
+	Added     time.Time `json:"added"`
+	// Due 调度窗口下次开启的时间
+	Due time.Time `json:"due"`
+
+	// dispatched 已交给命令执行，执行完成后移除；未完成时仍保留在文件中，重启后重新执行
+	dispatched bool
+}
+
+func (p PendingItem) key() string {
+	return p.MonitorID + "\x00" + p.Path
+}
+
+func (p PendingItem) event() model.FileEvent {
+	return model.FileEvent{
+		Type:      model.FileEventType(p.EventType),
+		Path:      p.Path,
+		OldPath:   p.OldPath,
+		Directory: p.Directory,
+		Timestamp: p.Added,
+	}
+}
+
+// PendingFile 返回配置对应的延后事件文件路径
+func PendingFile(cfg *config.Config) string {
+	dataDir := cfg.Settings.DataDir
+	if dataDir == "" {
+		dataDir = config.DefaultDataDir
+	}
+	return filepath.Join(dataDir, PendingFileName)
+}
+
+// LoadPending 读取延后事件文件，文件不存在时返回空列表
+func LoadPending(path string) ([]PendingItem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read pending events: %v", err)
+	}
+	var items []PendingItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse pending events: %v", err)
+	}
+	return items, nil
+}
+
+// loadPending 启动时加载延后事件；失败时告警并从空集合开始
+func (m *Monitor) loadPending() {
+	items, err := LoadPending(m.pendingPath)
+	if err != nil {
+		m.logger.Warn("[Monitor] 加载延后执行的事件失败: %v", err)
+		return
+	}
+	m.pendingMu.Lock()
+	for _, item := range items {
+		m.pending[item.key()] = item
+	}
+	m.pendingMu.Unlock()
+	if len(items) > 0 {
+		m.logger.Info("[Monitor] 加载了 %d 个延后执行的事件", len(items))
+	}
+}
+
+// PendingItems 返回按到期时间排序的延后事件
+func (m *Monitor) PendingItems() []PendingItem {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+	return m.sortedPendingLocked()
+}
+
+func (m *Monitor) sortedPendingLocked() []PendingItem {
+	items := make([]PendingItem, 0, len(m.pending))
+	for _, item := range m.pending {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Due.Equal(items[j].Due) {
+			return items[i].Due.Before(items[j].Due)
+		}
+		return items[i].key() < items[j].key()
+	})
+	return items
+}
+
+// savePendingLocked 原子写入延后事件文件
+func (m *Monitor) savePendingLocked() {
+	data, err := json.MarshalIndent(m.sortedPendingLocked(), "", "  ")
+	if err != nil {
+		m.logger.Error("[Monitor] 编码延后事件失败: %v", err)
+		return
+	}
+	if err := writeFileAtomic(m.pendingPath, data); err != nil {
+		m.logger.Error("[Monitor] 保存延后事件失败: %v", err)
+	}
+}
+
+// writeFileAtomic 写入临时文件并 fsync 后重命名为 path
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), dispositionDirPerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// deferEvent 将不在调度时间内的事件加入延后集合，调度窗口开启时执行
+func (m *Monitor) deferEvent(monitor config.Monitor, event model.FileEvent) {
+	now := m.clock.Now()
+	log := m.execLogger(monitor, event)
+	due, err := m.nextScheduleWindow(monitor, now)
+	if err != nil {
+		log.WithFields(logger.Err(err)).Error("[Monitor] 无法计算下次调度时间，事件未延后")
+		return
+	}
+
+	item := PendingItem{
+		MonitorID: monitorKey(monitor),
+		Path:      event.Path,
+		OldPath:   event.OldPath,
+		EventType: string(event.Type),
+		Directory: event.Directory,
+		Added:     now,
+		Due:       due,
+	}
+
+	m.pendingMu.Lock()
+	if existing, ok := m.pending[item.key()]; ok && !existing.dispatched {
+		item.Added = existing.Added
+	}
+	m.pending[item.key()] = item
+	m.savePendingLocked()
+	m.pendingMu.Unlock()
+
+	log.WithFields(logger.String("due", due.Format(time.RFC3339))).Info("[Monitor] 不在调度时间内，事件延后到下次调度窗口执行")
+	m.schedulePendingFlush()
+}
+
+// schedulePendingFlush 将定时器设置为最早到期的延后事件
+func (m *Monitor) schedulePendingFlush() {
+	m.pendingMu.Lock()
+	defer m.pendingMu.Unlock()
+
+	if m.pendingTimer != nil {
+		m.pendingTimer.Stop()
+		m.pendingTimer = nil
+	}
+	if atomic.LoadInt32(&m.stopped) == 1 {
+		return
+	}
+
+	var next time.Time
+	for _, item := range m.pending {
+		if item.dispatched {
+			continue
+		}
+		if next.IsZero() || item.Due.Before(next) {
+			next = item.Due
+		}
+	}
+	if next.IsZero() {
+		return
+	}
+
+	delay := next.Sub(m.clock.Now())
+	if delay < 0 {
+		delay = 0
+	}
+	m.pendingTimer = m.clock.AfterFunc(delay, m.flushPending)
+}
+
+// reschedulePending 配置变化后按当前调度表达式重新计算到期时间
+func (m *Monitor) reschedulePending() {
+	monitors := make(map[string]config.Monitor)
+	for _, monitor := range m.currentConfig().Monitors {
+		monitors[monitorKey(monitor)] = monitor
+	}
+
+	now := m.clock.Now()
+	m.pendingMu.Lock()
+	for key, item := range m.pending {
+		monitor, ok := monitors[item.MonitorID]
+		if item.dispatched || !ok || !monitor.HasSchedule() {
+			continue
+		}
+		if due, err := m.nextScheduleWindow(monitor, now); err == nil {
+			item.Due = due
+			m.pending[key] = item
+		}
+	}
+	m.savePendingLocked()
+	m.pendingMu.Unlock()
+
+	m.schedulePendingFlush()
+}
+
+// flushPending 执行已到期的延后事件
+func (m *Monitor) flushPending() {
+	if atomic.LoadInt32(&m.stopped) == 1 {
+		return
+	}
+	now := m.clock.Now()
+
+	monitors := make(map[string]config.Monitor)
+	for _, monitor := range m.currentConfig().Monitors {
+		if monitor.Enabled {
+			monitors[monitorKey(monitor)] = monitor
+		}
+	}
+
+	// 与调度窗口内的处理一致，按监控项与目录分组到期的事件
+	groups := make(map[bucketKey][]PendingItem)
+	var order []bucketKey
+	m.pendingMu.Lock()
+	for _, item := range m.sortedPendingLocked() {
+		if item.dispatched || item.Due.After(now) {
+			continue
+		}
+		monitor, ok := monitors[item.MonitorID]
+		if !ok {
+			m.logger.WithFields(logger.String("monitor_id", item.MonitorID), logger.String("path", item.Path)).
+				Warn("[Monitor] 监控项已删除或禁用，丢弃延后的事件")
+			delete(m.pending, item.key())
+			continue
+		}
+		if _, err := os.Stat(item.Path); err != nil && item.EventType != string(model.FileDeleted) {
+			m.logger.WithFields(logger.String("monitor_id", item.MonitorID), logger.String("path", item.Path)).
+				Info("[Monitor] 延后的文件已不存在，丢弃")
+			delete(m.pending, item.key())
+			continue
+		}
+		if !m.isScheduleActive(monitor) {
+			// 到期时仍不在调度时间内（如调度表达式或日历已变更），重新计算
+			if due, err := m.nextScheduleWindow(monitor, now); err == nil && due.After(now) {
+				item.Due = due
+				m.pending[item.key()] = item
+				continue
+			}
+		}
+		item.dispatched = true
+		m.pending[item.key()] = item
+		key := bucketKey{monitor: item.MonitorID, dir: filepath.Dir(item.Path)}
+		if _, exists := groups[key]; !exists {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], item)
+	}
+	m.savePendingLocked()
+	m.pendingMu.Unlock()
+
+	for _, key := range order {
+		items := groups[key]
+		events := make([]model.FileEvent, 0, len(items))
+		for _, item := range items {
+			events = append(events, item.event())
+		}
+		m.logger.WithFields(logger.String("monitor_id", key.monitor), logger.String("dir", key.dir), logger.Int("file_count", len(events))).
+			Info("[Monitor] 调度窗口已开启，执行延后的事件")
+
+		tracker := &ackTracker{}
+		m.dispatchReady(monitors[key.monitor], key.dir, events, tracker)
+		m.completePendingWhenDone(tracker, items)
+	}
+
+	m.schedulePendingFlush()
+}
+
+// completePendingWhenDone 执行结束后移除延后事件；执行被取消时保留，重启后重新执行
+func (m *Monitor) completePendingWhenDone(t *ackTracker, items []PendingItem) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		t.wg.Wait()
+
+		m.pendingMu.Lock()
+		defer m.pendingMu.Unlock()
+		for _, item := range items {
+			current, ok := m.pending[item.key()]
+			if !ok || !current.dispatched {
+				// 执行期间同一文件再次被延后
+				continue
+			}
+			if atomic.LoadInt32(&t.cancelled) == 1 {
+				current.dispatched = false
+				m.pending[item.key()] = current
+				continue
+			}
+			delete(m.pending, item.key())
+		}
+		m.savePendingLocked()
+	}()
+}
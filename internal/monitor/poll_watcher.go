@@ -0,0 +1,327 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// DefaultPollInterval default interval between two directory scans
+	DefaultPollInterval = 2 * time.Second
+)
+
+// fileState snapshot entry used to detect changes between two scans
+type fileState struct {
+	dev   uint64
+	ino   uint64
+	size  int64
+	mtime time.Time
+}
+
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+// PollWatcher Watcher implementation that periodically scans directory trees.
+// It works on filesystems where inotify never sees remote changes (NFS, SMB/CIFS, FUSE).
+type PollWatcher struct {
+	logger   *logger.Logger
+	interval time.Duration
+
+	mu        sync.Mutex
+	snapshots map[string]map[string]fileState // base dir -> path -> state
+	depths    map[string]int                  // base dir -> depth
+
+	events     chan model.FileEvent
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	startOnce  sync.Once
+	errorCount uint64
+}
+
+var _ Watcher = (*PollWatcher)(nil)
+var _ WatchInspector = (*PollWatcher)(nil)
+
+// NewPollWatcher Create a polling watcher; interval <= 0 uses DefaultPollInterval
+func NewPollWatcher(logger *logger.Logger, interval time.Duration) *PollWatcher {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PollWatcher{
+		logger:    logger,
+		interval:  interval,
+		snapshots: make(map[string]map[string]fileState),
+		depths:    make(map[string]int),
+		events:    make(chan model.FileEvent, DefaultEventChannelBuffer),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Watch starts polling dir down to depth; the first scan is the baseline and emits no events.
+// New subdirectories are picked up by the next scan.
+func (pw *PollWatcher) Watch(dir string, depth int) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("stat failed for %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	snapshot, err := scanTree(dir, depth)
+	if err != nil {
+		return fmt.Errorf("initial scan failed for %s: %w", dir, err)
+	}
+
+	pw.mu.Lock()
+	pw.snapshots[dir] = snapshot
+	pw.depths[dir] = depth
+	pw.mu.Unlock()
+
+	pw.startOnce.Do(func() {
+		pw.wg.Add(1)
+		go pw.pollLoop()
+	})
+	return nil
+}
+
+// Unwatch stops polling dir
+func (pw *PollWatcher) Unwatch(dir string) error {
+	pw.mu.Lock()
+	delete(pw.snapshots, dir)
+	delete(pw.depths, dir)
+	pw.mu.Unlock()
+	return nil
+}
+
+// Rewatch re-establishes the baseline for dir (e.g. after the directory was recreated)
+func (pw *PollWatcher) Rewatch(dir string) error {
+	pw.mu.Lock()
+	depth := pw.depths[dir]
+	pw.mu.Unlock()
+	return pw.Watch(dir, depth)
+}
+
+// IsWatching reports whether dir is being polled
+func (pw *PollWatcher) IsWatching(dir string) bool {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	_, ok := pw.snapshots[dir]
+	return ok
+}
+
+// WatchCount returns the number of polled directory trees
+func (pw *PollWatcher) WatchCount() int {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return len(pw.snapshots)
+}
+
+// ErrorCount returns the total number of failed scans
+func (pw *PollWatcher) ErrorCount() uint64 {
+	return atomic.LoadUint64(&pw.errorCount)
+}
+
+// Events returns the event channel
+func (pw *PollWatcher) Events() <-chan model.FileEvent {
+	return pw.events
+}
+
+// Close stops polling and closes the event channel
+func (pw *PollWatcher) Close() error {
+	pw.cancel()
+	// close the channel here if the poll loop was never started
+	pw.startOnce.Do(func() { close(pw.events) })
+	pw.wg.Wait()
+	pw.mu.Lock()
+	pw.snapshots = make(map[string]map[string]fileState)
+	pw.depths = make(map[string]int)
+	pw.mu.Unlock()
+	return nil
+}
+
+func (pw *PollWatcher) pollLoop() {
+	defer pw.wg.Done()
+	defer close(pw.events)
+
+	ticker := time.NewTicker(pw.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-pw.ctx.Done():
+			return
+		case <-ticker.C:
+			pw.pollOnce()
+		}
+	}
+}
+
+// pollOnce scans every watched tree and emits the differences
+func (pw *PollWatcher) pollOnce() {
+	pw.mu.Lock()
+	dirs := make([]string, 0, len(pw.snapshots))
+	depths := make(map[string]int, len(pw.depths))
+	for dir := range pw.snapshots {
+		dirs = append(dirs, dir)
+		depths[dir] = pw.depths[dir]
+	}
+	pw.mu.Unlock()
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		current, err := scanTree(dir, depths[dir])
+		if err != nil {
+			atomic.AddUint64(&pw.errorCount, 1)
+			pw.logger.WithFields(logger.String("dir", dir), logger.Err(err)).Warn("[PollWatcher] Scan failed")
+			continue
+		}
+
+		pw.mu.Lock()
+		previous, ok := pw.snapshots[dir]
+		pw.mu.Unlock()
+		if !ok {
+			// unwatched while scanning
+			continue
+		}
+
+		for _, event := range diffSnapshots(previous, current, time.Now()) {
+			select {
+			case pw.events <- event:
+				pw.logger.WithFields(eventFields(event)...).Debug("[PollWatcher] 文件事件已发送到事件通道")
+			case <-pw.ctx.Done():
+				return
+			default:
+				metricEventsDropped.Inc(dropStageWatcher)
+				pw.logger.WithFields(eventFields(event)...).Info("[PollWatcher] 事件通道已满，丢弃事件，下次扫描重新发送")
+				revertSnapshot(previous, current, event)
+			}
+		}
+
+		// 全部事件发送后才提交快照：被丢弃的变化保留在差异中
+		pw.mu.Lock()
+		if _, ok := pw.snapshots[dir]; ok {
+			pw.snapshots[dir] = current
+		}
+		pw.mu.Unlock()
+	}
+}
+
+// revertSnapshot restores the previous state of the paths of a dropped event in current,
+// so the next scan reports the same change again
+func revertSnapshot(previous, current map[string]fileState, event model.FileEvent) {
+	paths := []string{event.Path}
+	if event.OldPath != "" {
+		paths = append(paths, event.OldPath)
+	}
+	for _, path := range paths {
+		if st, ok := previous[path]; ok {
+			current[path] = st
+		} else {
+			delete(current, path)
+		}
+	}
+}
+
+// diffSnapshots compares two scans. A path that disappeared while a new path with
+// the same device/inode appeared is reported as a rename.
+func diffSnapshots(previous, current map[string]fileState, now time.Time) []model.FileEvent {
+	var events []model.FileEvent
+	newEvent := func(t model.FileEventType, path string, st fileState) model.FileEvent {
+		return model.FileEvent{
+			Type:      t,
+			Path:      path,
+			Directory: filepath.Dir(path),
+			Timestamp: now,
+			Size:      st.size,
+			ModTime:   st.mtime,
+		}
+	}
+
+	removed := make(map[string]bool)
+	removedByKey := make(map[fileKey]string)
+	for path, old := range previous {
+		if _, ok := current[path]; !ok {
+			removed[path] = true
+			if old.ino != 0 {
+				removedByKey[fileKey{old.dev, old.ino}] = path
+			}
+		}
+	}
+
+	created := make([]string, 0)
+	for path, st := range current {
+		old, ok := previous[path]
+		switch {
+		case !ok:
+			created = append(created, path)
+		case old.ino != st.ino || old.dev != st.dev || old.size != st.size || !old.mtime.Equal(st.mtime):
+			events = append(events, newEvent(model.FileModified, path, st))
+		}
+	}
+	sort.Strings(created)
+
+	for _, path := range created {
+		st := current[path]
+		if oldPath, ok := removedByKey[fileKey{st.dev, st.ino}]; ok && st.ino != 0 && removed[oldPath] {
+			delete(removed, oldPath)
+			event := newEvent(model.FileRenamed, path, st)
+			event.OldPath = oldPath
+			events = append(events, event)
+			continue
+		}
+		events = append(events, newEvent(model.FileCreated, path, st))
+	}
+
+	deleted := make([]string, 0, len(removed))
+	for path := range removed {
+		deleted = append(deleted, path)
+	}
+	sort.Strings(deleted)
+	for _, path := range deleted {
+		events = append(events, newEvent(model.FileDeleted, path, previous[path]))
+	}
+	return events
+}
+
+// scanTree walks root down to depth and records regular files; unreadable entries are skipped
+func scanTree(root string, depth int) (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == root {
+				return err
+			}
+			// file removed or unreadable during the walk
+			return nil
+		}
+		if info.IsDir() && !withinDepth(root, p, depth) {
+			return filepath.SkipDir
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		st := fileState{size: info.Size(), mtime: info.ModTime()}
+		if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+			st.dev = uint64(sys.Dev)
+			st.ino = uint64(sys.Ino)
+		}
+		snapshot[p] = st
+		return nil
+	})
+	return snapshot, err
+}
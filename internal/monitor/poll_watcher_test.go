@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/model"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	previous := map[string]fileState{
+		"/d/keep.txt":   {dev: 1, ino: 10, size: 1, mtime: t0},
+		"/d/grow.txt":   {dev: 1, ino: 11, size: 1, mtime: t0},
+		"/d/old.txt":    {dev: 1, ino: 12, size: 3, mtime: t0},
+		"/d/gone.txt":   {dev: 1, ino: 13, size: 1, mtime: t0},
+		"/d/noino1.txt": {size: 1, mtime: t0},
+	}
+	current := map[string]fileState{
+		"/d/keep.txt":   {dev: 1, ino: 10, size: 1, mtime: t0},
+		"/d/grow.txt":   {dev: 1, ino: 11, size: 5, mtime: t0.Add(time.Second)},
+		"/d/new.txt":    {dev: 1, ino: 12, size: 3, mtime: t0},
+		"/d/fresh.txt":  {dev: 1, ino: 14, size: 1, mtime: t0},
+		"/d/noino2.txt": {size: 1, mtime: t0},
+	}
+
+	events := diffSnapshots(previous, current, t0)
+	got := make(map[string]model.FileEvent)
+	for _, e := range events {
+		got[e.Path] = e
+	}
+
+	want := map[string]model.FileEventType{
+		"/d/grow.txt":   model.FileModified,
+		"/d/new.txt":    model.FileRenamed,
+		"/d/fresh.txt":  model.FileCreated,
+		"/d/noino2.txt": model.FileCreated,
+		"/d/gone.txt":   model.FileDeleted,
+		"/d/noino1.txt": model.FileDeleted,
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for path, typ := range want {
+		if got[path].Type != typ {
+			t.Errorf("%s: type = %q, want %q", path, got[path].Type, typ)
+		}
+	}
+	if old := got["/d/new.txt"].OldPath; old != "/d/old.txt" {
+		t.Errorf("rename old path = %q", old)
+	}
+	if got["/d/grow.txt"].Size != 5 {
+		t.Errorf("modified size = %d", got["/d/grow.txt"].Size)
+	}
+}
+
+func TestPollWatcherRetriesDroppedEvents(t *testing.T) {
+	dir := t.TempDir()
+	pw := NewPollWatcher(newTestExecutor().logger, time.Hour)
+	pw.events = make(chan model.FileEvent, 1)
+	defer pw.Close()
+	if err := pw.Watch(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pw.pollOnce()
+	first := <-pw.events
+
+	// 通道已满时丢弃的事件在下次扫描中重新发送
+	pw.pollOnce()
+	select {
+	case second := <-pw.events:
+		if second.Path == first.Path || second.Type != model.FileCreated {
+			t.Fatalf("second event = %+v, first was %s", second, first.Path)
+		}
+	default:
+		t.Fatal("dropped event was not reported by the next scan")
+	}
+	pw.pollOnce()
+	select {
+	case e := <-pw.events:
+		t.Fatalf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestScanTreeDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"top.txt", "a/one.txt", "a/b/two.txt"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		depth int
+		want  int
+	}{{0, 1}, {1, 2}, {config.UnlimitedDepth, 3}} {
+		snapshot, err := scanTree(root, tt.depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshot) != tt.want {
+			t.Errorf("depth %d: got %d files, want %d", tt.depth, len(snapshot), tt.want)
+		}
+	}
+}
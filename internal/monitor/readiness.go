@@ -0,0 +1,287 @@
+package monitor
+
+import (
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+// rejectNotReady 等待写入完成超时（指标 reason 标签值）
+const rejectNotReady = "not_ready"
+
+// readinessWait 已过静默期、等待写入完成的一组文件
+type readinessWait struct {
+	monitor  string
+	dir      string
+	events   map[string]model.FileEvent
+	seqs     []uint64
+	deadline time.Time
+	// size_stable：上次检查的大小与修改时间，以及连续不变的次数
+	observed map[string]fileState
+	stable   map[string]int
+}
+
+// readinessStrategy 返回监控项的写入完成判断策略
+func readinessStrategy(monitor config.Monitor) string {
+	if monitor.Readiness == nil || monitor.Readiness.Strategy == "" {
+		return config.ReadinessQuiet
+	}
+	return monitor.Readiness.Strategy
+}
+
+// readinessInterval 返回两次检查的间隔，未设置时使用 settings.min_stability_time_ms
+func readinessInterval(monitor config.Monitor, settings model.Settings) time.Duration {
+	ms := settings.MinStabilityTimeMs
+	if monitor.Readiness != nil && monitor.Readiness.IntervalMs > 0 {
+		ms = monitor.Readiness.IntervalMs
+	}
+	if ms <= 0 {
+		ms = config.DefaultMinStabilityTimeMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func markerSuffix(monitor config.Monitor) string {
+	if monitor.Readiness != nil && monitor.Readiness.MarkerSuffix != "" {
+		return monitor.Readiness.MarkerSuffix
+	}
+	return config.DefaultReadinessMarkerSuffix
+}
+
+// newReadinessWait 创建等待组，超时时间按监控项配置计算
+func (m *Monitor) newReadinessWait(monitor config.Monitor, dir string) *readinessWait {
+	timeout := config.DefaultReadinessTimeoutSeconds
+	if monitor.Readiness != nil && monitor.Readiness.TimeoutSeconds > 0 {
+		timeout = monitor.Readiness.TimeoutSeconds
+	}
+	return &readinessWait{
+		monitor:  monitorKey(monitor),
+		dir:      dir,
+		events:   make(map[string]model.FileEvent),
+		deadline: m.clock.Now().Add(time.Duration(timeout) * time.Second),
+		observed: make(map[string]fileState),
+		stable:   make(map[string]int),
+	}
+}
+
+// splitByReadiness 返回已写入完成的事件，未完成的事件放入 w 等待下次检查；
+// marker 策略的标记文件本身不会触发命令
+func (m *Monitor) splitByReadiness(monitor config.Monitor, events []model.FileEvent, w *readinessWait) []model.FileEvent {
+	strategy := readinessStrategy(monitor)
+	if strategy == config.ReadinessQuiet {
+		return events
+	}
+
+	ready := make([]model.FileEvent, 0, len(events))
+	for _, event := range events {
+		if strategy == config.ReadinessMarker && strings.HasSuffix(event.Path, markerSuffix(monitor)) {
+			m.logger.WithFields(eventFields(event)...).Debug("[Monitor] 标记文件，不触发命令")
+			continue
+		}
+		// 删除事件没有需要等待的写入
+		if event.Type == model.FileDeleted || m.fileReady(monitor, strategy, event.Path, w) {
+			delete(w.events, event.Path)
+			ready = append(ready, event)
+			continue
+		}
+		w.events[event.Path] = event
+	}
+	return ready
+}
+
+// fileReady 按策略判断文件是否已写入完成
+func (m *Monitor) fileReady(monitor config.Monitor, strategy, path string, w *readinessWait) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		// 文件已不存在，交给后续流程跳过
+		return true
+	}
+
+	switch strategy {
+	case config.ReadinessCloseWrite:
+		m.dirMu.Lock()
+		tracker := m.closeWrites
+		m.dirMu.Unlock()
+		if tracker == nil {
+			// inotify 不可用时退化为 size_stable
+			return m.sizeStable(monitor, path, info, w)
+		}
+		ready, known := tracker.ready(path)
+		if !known {
+			// 事件队列溢出后文件的写入状态未知，按 size_stable 判断
+			return m.sizeStable(monitor, path, info, w)
+		}
+		return ready
+	case config.ReadinessSizeStable:
+		return m.sizeStable(monitor, path, info, w)
+	case config.ReadinessMarker:
+		_, err := os.Stat(path + markerSuffix(monitor))
+		return err == nil
+	case config.ReadinessExclusiveOpen:
+		return exclusiveOpen(path)
+	}
+	return true
+}
+
+// sizeStable 大小与修改时间连续 checks 次检查不变，且修改时间已超过 min_stability_time_ms
+func (m *Monitor) sizeStable(monitor config.Monitor, path string, info os.FileInfo, w *readinessWait) bool {
+	checks := config.DefaultReadinessChecks
+	if monitor.Readiness != nil && monitor.Readiness.Checks > 0 {
+		checks = monitor.Readiness.Checks
+	}
+	current := fileState{size: info.Size(), mtime: info.ModTime()}
+	previous, seen := w.observed[path]
+	w.observed[path] = current
+	if !seen || previous != current {
+		w.stable[path] = 0
+		return false
+	}
+	w.stable[path]++
+	return w.stable[path] >= checks && m.isFileStable(path)
+}
+
+// awaitReadiness 登记等待写入完成的文件并安排下次检查；调用方持有 dirMu
+func (m *Monitor) awaitReadiness(monitor config.Monitor, w *readinessWait) {
+	for path := range w.events {
+		key := w.monitor + "\x00" + path
+		if other, ok := m.awaiting[key]; ok && other != w {
+			// 检查期间文件已由另一个等待组登记，交给该等待组处理
+			delete(w.events, path)
+			continue
+		}
+		m.awaiting[key] = w
+	}
+	interval := readinessInterval(monitor, m.currentConfig().Settings)
+	m.logger.WithFields(logger.String("monitor_id", w.monitor), logger.String("dir", w.dir), logger.Int("file_count", len(w.events))).
+		Info("[Monitor] 文件尚未写入完成，等待后再次检查: 策略=%s, 间隔=%v", readinessStrategy(monitor), interval)
+	m.clock.AfterFunc(interval, func() { m.checkReadiness(w) })
+}
+
+// isAwaiting 判断文件是否正在等待写入完成；调用方持有 dirMu
+func (m *Monitor) isAwaiting(monitor config.Monitor, path string) bool {
+	_, ok := m.awaiting[monitorKey(monitor)+"\x00"+path]
+	return ok
+}
+
+// checkReadiness 再次检查等待中的文件：已完成的交给命令，超时的跳过，其余继续等待。
+// 检查文件与执行命令时不持有 dirMu
+func (m *Monitor) checkReadiness(w *readinessWait) {
+	if atomic.LoadInt32(&m.stopped) == 1 {
+		return
+	}
+
+	var monitor config.Monitor
+	found := false
+	for _, candidate := range m.currentConfig().Monitors {
+		if candidate.Enabled && monitorKey(candidate) == w.monitor {
+			monitor, found = candidate, true
+			break
+		}
+	}
+	if !found {
+		m.logger.WithFields(logger.String("monitor_id", w.monitor), logger.String("dir", w.dir)).
+			Warn("[Monitor] 监控项已删除或禁用，丢弃等待写入完成的 %d 个文件", len(w.events))
+		m.finishReadiness(monitor, w, nil)
+		return
+	}
+
+	ready := m.splitByReadiness(monitor, sortedEvents(w.events), w)
+	m.dirMu.Lock()
+	for _, event := range ready {
+		delete(m.awaiting, w.monitor+"\x00"+event.Path)
+	}
+	m.dirMu.Unlock()
+
+	if len(w.events) > 0 && !m.clock.Now().Before(w.deadline) {
+		for _, event := range sortedEvents(w.events) {
+			metricFilesRejected.Inc(w.monitor, rejectNotReady)
+			m.logger.WithFields(logger.String("monitor_id", w.monitor), logger.String("path", event.Path), logger.String("reason", rejectNotReady)).
+				Warn("[Monitor] 等待写入完成超时，跳过文件")
+		}
+		m.finishReadiness(monitor, w, ready)
+		return
+	}
+	if len(w.events) == 0 {
+		m.finishReadiness(monitor, w, ready)
+		return
+	}
+
+	// 其余文件继续等待，日志序号在全部文件处理后释放
+	if len(ready) > 0 {
+		m.executeReadyEvents(monitor, w.dir, ready, nil)
+	}
+	m.clock.AfterFunc(readinessInterval(monitor, m.currentConfig().Settings), func() { m.checkReadiness(w) })
+}
+
+// finishReadiness 结束等待组：处理已完成的文件，执行后释放日志序号
+func (m *Monitor) finishReadiness(monitor config.Monitor, w *readinessWait, ready []model.FileEvent) {
+	m.dirMu.Lock()
+	for path := range w.events {
+		delete(m.awaiting, w.monitor+"\x00"+path)
+	}
+	w.events = nil
+	if len(ready) == 0 {
+		acks := m.releaseSeqsLocked(w.seqs)
+		m.dirMu.Unlock()
+		m.ackEvents(acks...)
+		return
+	}
+	m.dirMu.Unlock()
+	m.executeReadyEvents(monitor, w.dir, ready, w.seqs)
+}
+
+// closeWriteDirs 返回使用 close_write 策略的监控目录及其子目录层数
+func closeWriteDirs(cfg *config.Config) map[string]int {
+	dirs := make(map[string]int)
+	for _, monitor := range cfg.Monitors {
+		if !monitor.Enabled || readinessStrategy(monitor) != config.ReadinessCloseWrite {
+			continue
+		}
+		depth, ok := dirs[monitor.Directory]
+		d := monitor.WatchDepth()
+		switch {
+		case !ok, d == config.UnlimitedDepth:
+			dirs[monitor.Directory] = d
+		case depth != config.UnlimitedDepth && d > depth:
+			dirs[monitor.Directory] = d
+		}
+	}
+	return dirs
+}
+
+// syncCloseWrites 按配置注册或取消 close_write 跟踪的目录，首次需要时创建 inotify 实例
+func (m *Monitor) syncCloseWrites(cfg *config.Config) {
+	dirs := closeWriteDirs(cfg)
+	m.dirMu.Lock()
+	tracker := m.closeWrites
+	m.dirMu.Unlock()
+	if tracker == nil {
+		if len(dirs) == 0 {
+			return
+		}
+		var err error
+		if tracker, err = newCloseWriteTracker(m.logger); err != nil {
+			m.logger.WithFields(logger.Err(err)).Error("[Monitor] 无法跟踪 close_write 事件，close_write 策略按 size_stable 处理")
+			return
+		}
+		m.dirMu.Lock()
+		m.closeWrites = tracker
+		m.dirMu.Unlock()
+	}
+
+	for base, depth := range tracker.watchedBases() {
+		if d, ok := dirs[base]; !ok || d != depth {
+			tracker.unwatch(base)
+		}
+	}
+	for dir, depth := range dirs {
+		if err := tracker.watch(dir, depth); err != nil {
+			m.logger.WithFields(logger.String("dir", dir), logger.Err(err)).Error("[Monitor] 注册 close_write 跟踪失败")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/model"
+)
+
+func TestExclusiveOpenUncooperativeWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.dat")
+	// 与 SFTP、scp 等写入方相同：以写方式打开文件但不加锁
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exclusiveOpen(path) {
+		t.Fatal("file open for writing reported as ready")
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !exclusiveOpen(path) {
+		t.Fatal("closed file reported as still being written")
+	}
+}
+
+func TestCloseWriteQueueOverflow(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	h := newMonitorHarness(t, testNow, config.Monitor{
+		Name: "m", Command: "true", FilePatterns: []string{"*"},
+		Readiness: &config.ReadinessConfig{Strategy: config.ReadinessCloseWrite, Checks: 1, IntervalMs: int(interval / time.Millisecond)},
+	})
+	path := filepath.Join(h.dir, "lost.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if ready, _ := h.m.closeWrites.ready(path); !ready {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("close_write tracker did not see the write")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	h.emitEvent(model.FileEvent{Type: model.FileCreated, Path: path})
+	h.clock.Advance(testQuiet)
+	if records := h.collect(); len(records) != 0 {
+		t.Fatalf("records = %+v before the file was closed", records)
+	}
+
+	// 溢出丢失了 IN_CLOSE_WRITE：文件改为按大小是否稳定判断，而不是一直等待
+	h.m.closeWrites.handleEvent(-1, syscall.IN_Q_OVERFLOW, "")
+	if _, known := h.m.closeWrites.ready(path); known {
+		t.Fatal("file state still known after queue overflow")
+	}
+	h.clock.Advance(interval)
+	if records := h.collect(); len(records) != 0 {
+		t.Fatalf("records = %+v after a single size check", records)
+	}
+	h.clock.Advance(interval)
+	if records := h.collect(); len(records) != 1 || !equalStrings(records[0].paths, []string{path}) {
+		t.Fatalf("records = %+v, want %s", records, path)
+	}
+}
@@ -0,0 +1,245 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"dir-monitor-go/internal/config"
+)
+
+// configDiff 新旧配置中监控项的差异（按监控项 ID 比较）
+type configDiff struct {
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Unchanged []string
+}
+
+func (d configDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// monitorKey 返回监控项的唯一标识，未配置 ID 时退化为目录+命令
+func monitorKey(mon config.Monitor) string {
+	if mon.ID != "" {
+		return mon.ID
+	}
+	return mon.Directory + "|" + mon.CommandLine()
+}
+
+// diffMonitors 按监控项 ID 比较新旧配置
+func diffMonitors(oldMonitors, newMonitors []config.Monitor) configDiff {
+	oldByKey := make(map[string]config.Monitor, len(oldMonitors))
+	for _, mon := range oldMonitors {
+		oldByKey[monitorKey(mon)] = mon
+	}
+
+	var diff configDiff
+	seen := make(map[string]bool, len(newMonitors))
+	for _, mon := range newMonitors {
+		key := monitorKey(mon)
+		seen[key] = true
+		old, exists := oldByKey[key]
+		switch {
+		case !exists:
+			diff.Added = append(diff.Added, key)
+		case !sameMonitor(old, mon):
+			diff.Changed = append(diff.Changed, key)
+		default:
+			diff.Unchanged = append(diff.Unchanged, key)
+		}
+	}
+	for key := range oldByKey {
+		if !seen[key] {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// sameMonitor 比较两个监控项的可序列化字段是否一致
+func sameMonitor(a, b config.Monitor) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
+
+// enabledDirectories 返回配置中启用监控项对应的目录集合
+func enabledDirectories(cfg *config.Config) map[string]bool {
+	dirs := make(map[string]bool)
+	for _, mon := range cfg.Monitors {
+		if mon.Enabled {
+			dirs[mon.Directory] = true
+		}
+	}
+	return dirs
+}
+
+// Reload 将新配置应用到运行中的监控器。
+// 新增目录会被加入监控，不再需要的目录会被移除；命令、文件模式、调度等字段原地替换。
+// 任一目录无法监控时撤销本次的目录注册并返回错误，继续使用旧配置。
+// 正在执行的命令不受影响（它们持有旧监控项的副本）。
+func (m *Monitor) Reload(newCfg *config.Config) error {
+	if newCfg == nil {
+		return fmt.Errorf("new configuration is nil")
+	}
+	if err := newCfg.Validate(); err != nil {
+		return fmt.Errorf("configuration validation failed: %v", err)
+	}
+	if m.specificDir != "" {
+		return fmt.Errorf("reload is not supported when watching a specific directory")
+	}
+
+	schedules, err := loadScheduleData(newCfg)
+	if err != nil {
+		return fmt.Errorf("failed to load schedule calendars: %v", err)
+	}
+	filePatterns, err := compileFilePatterns(newCfg)
+	if err != nil {
+		return err
+	}
+
+	oldCfg := m.currentConfig()
+	diff := diffMonitors(oldCfg.Monitors, newCfg.Monitors)
+
+	m.logger.Info("[Monitor] 配置重载 - 新增: %v, 移除: %v, 变更: %v, 未变更: %d",
+		diff.Added, diff.Removed, diff.Changed, len(diff.Unchanged))
+	m.warnRestartOnlySettings(oldCfg, newCfg)
+
+	oldDirs := enabledDirectories(oldCfg)
+	newDirs := enabledDirectories(newCfg)
+
+	// 先注册全部新目录，任一目录失败时撤销已做的注册并保留旧配置
+	if err := m.watchReloadedDirs(newCfg, oldDirs); err != nil {
+		return err
+	}
+
+	m.cfgMu.Lock()
+	m.config = newCfg
+	m.schedules = schedules
+	m.filePatterns = filePatterns
+	m.cfgMu.Unlock()
+	m.reloadIgnoreRules(newCfg)
+	m.syncCloseWrites(newCfg)
+
+	// 替换配置后再移除旧目录，避免缓冲中的事件匹配到已删除的监控项
+	for dir := range oldDirs {
+		if newDirs[dir] {
+			continue
+		}
+		if err := m.unwatchDirectory(dir); err != nil {
+			m.logger.Error("[Monitor] 配置重载: 取消监控目录失败 %s: %v", dir, err)
+			continue
+		}
+		m.logger.Info("[Monitor] 配置重载: 停止监控目录: %s", dir)
+	}
+
+	// 调度表达式可能已变更，重新计算延后事件的执行时间
+	m.reschedulePending()
+
+	if diff.empty() {
+		m.logger.Info("[Monitor] 配置重载完成，监控项无变化")
+	} else {
+		m.logger.Info("[Monitor] 配置重载完成，当前启用目录数: %d", len(newDirs))
+	}
+	return nil
+}
+
+// watchChange 配置重载时对一个目录监控的变更，用于失败时撤销
+type watchChange struct {
+	dir string
+	// backend 为空表示新增的目录，否则为重新注册前的后端与子目录层数
+	backend string
+	depth   int
+}
+
+// watchReloadedDirs 按新配置中监控项的顺序注册新增目录，后端或子目录层数变化的目录重新注册；
+// 任一目录失败时撤销本次的全部注册并返回错误
+func (m *Monitor) watchReloadedDirs(newCfg *config.Config, oldDirs map[string]bool) error {
+	var applied []watchChange
+	seen := make(map[string]bool)
+	for _, mon := range newCfg.Monitors {
+		dir := mon.Directory
+		if !mon.Enabled || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		backend := m.resolveBackend(newCfg, dir)
+		depth := m.resolveDepth(newCfg, dir)
+		change := watchChange{dir: dir}
+		if oldDirs[dir] {
+			current := m.watchBackend(dir)
+			if current == "" || (current == backend && m.watchDepth(dir) == depth) {
+				continue
+			}
+			// 监控后端或子目录层数变化时重新注册
+			change.backend, change.depth = current, m.watchDepth(dir)
+			if err := m.unwatchDirectory(dir); err != nil {
+				m.logger.Warn("[Monitor] 配置重载: 取消原目录监控失败 %s: %v", dir, err)
+			}
+		}
+		if err := m.watchDirectory(dir, backend, depth); err != nil {
+			m.logger.Error("[Monitor] 配置重载: 监控目录失败 %s: %v，撤销本次重载", dir, err)
+			m.revertWatchChanges(append(applied, change))
+			return fmt.Errorf("failed to watch directory %s: %v", dir, err)
+		}
+		applied = append(applied, change)
+		if change.backend != "" {
+			m.logger.Info("[Monitor] 配置重载: 目录监控已更新: %s, 后端=%s, 子目录层数=%d", dir, backend, depth)
+		} else {
+			m.logger.Info("[Monitor] 配置重载: 开始监控目录: %s", dir)
+		}
+	}
+	return nil
+}
+
+// revertWatchChanges 按相反顺序撤销目录监控变更：取消本次的注册，恢复原有的后端与子目录层数
+func (m *Monitor) revertWatchChanges(changes []watchChange) {
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if m.watchBackend(change.dir) != "" {
+			if err := m.unwatchDirectory(change.dir); err != nil {
+				m.logger.Warn("[Monitor] 配置重载: 撤销目录监控失败 %s: %v", change.dir, err)
+			}
+		}
+		if change.backend == "" {
+			continue
+		}
+		if err := m.watchDirectory(change.dir, change.backend, change.depth); err != nil {
+			m.logger.Error("[Monitor] 配置重载: 恢复原目录监控失败 %s: %v", change.dir, err)
+		}
+	}
+}
+
+// warnRestartOnlySettings 提示只能在重启后生效的全局设置
+func (m *Monitor) warnRestartOnlySettings(oldCfg, newCfg *config.Config) {
+	o, n := oldCfg.Settings, newCfg.Settings
+	if o.MaxConcurrentOperations != n.MaxConcurrentOperations {
+		m.logger.Warn("[Monitor] 配置重载: max_concurrent_operations 变更需重启后生效 (%d -> %d)",
+			o.MaxConcurrentOperations, n.MaxConcurrentOperations)
+	}
+	if o.EventChannelBufferSize != n.EventChannelBufferSize {
+		m.logger.Warn("[Monitor] 配置重载: event_channel_buffer_size 变更需重启后生效 (%d -> %d)",
+			o.EventChannelBufferSize, n.EventChannelBufferSize)
+	}
+	if o.LogLevel != n.LogLevel || o.LogFile != n.LogFile || o.LogMaxSize != n.LogMaxSize ||
+		o.LogShowCaller != n.LogShowCaller || o.LogFormat != n.LogFormat ||
+		oldCfg.LogLevel != newCfg.LogLevel || oldCfg.LogFile != newCfg.LogFile {
+		m.logger.Warn("[Monitor] 配置重载: 日志配置变更需重启后生效")
+	}
+	if o.DataDir != n.DataDir || o.HistoryRetentionDays != n.HistoryRetentionDays ||
+		o.HistoryOutputMaxBytes != n.HistoryOutputMaxBytes ||
+		o.JournalEnabled != n.JournalEnabled || o.JournalCompactThreshold != n.JournalCompactThreshold {
+		m.logger.Warn("[Monitor] 配置重载: data_dir、执行记录与预写日志配置变更需重启后生效")
+	}
+	if o.PollIntervalMs != n.PollIntervalMs {
+		m.logger.Warn("[Monitor] 配置重载: poll_interval_ms 变更需重启后生效 (%d -> %d)",
+			o.PollIntervalMs, n.PollIntervalMs)
+	}
+}
@@ -0,0 +1,57 @@
+package monitor
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"dir-monitor-go/internal/config"
+)
+
+func TestReloadFailureOnSecondMonitorKeepsOldConfig(t *testing.T) {
+	h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "true", FilePatterns: []string{"*.csv"}})
+	oldCfg := h.m.currentConfig()
+	added, broken := t.TempDir(), t.TempDir()
+	h.watcher.WatchErrs = map[string]error{broken: fmt.Errorf("permission denied")}
+
+	newCfg := *oldCfg
+	newCfg.Monitors = []config.Monitor{
+		// 第一个监控项改为递归，目录以新的子目录层数重新注册
+		{Name: "m", Directory: h.dir, Command: "true", FilePatterns: []string{"*.csv"}, Recursive: true},
+		{Name: "broken", Directory: broken, Command: "true", FilePatterns: []string{"*"}},
+		{Name: "added", Directory: added, Command: "true", FilePatterns: []string{"*"}},
+	}
+	for i := range newCfg.Monitors {
+		newCfg.Monitors[i].Enabled = true
+		newCfg.Monitors[i].Timeout = 10
+	}
+
+	if err := h.m.Reload(&newCfg); err == nil {
+		t.Fatal("reload should fail when a directory cannot be watched")
+	}
+	if h.m.currentConfig() != oldCfg {
+		t.Error("configuration was replaced by a failed reload")
+	}
+	if got := h.watcher.Watched(); !equalStrings(got, []string{h.dir}) {
+		t.Errorf("watched = %v, want only %s", got, h.dir)
+	}
+	if depth, _ := h.watcher.Depth(h.dir); depth != 0 {
+		t.Errorf("watch depth = %d, want the original 0", depth)
+	}
+
+	// 旧配置仍然生效
+	path := h.emit("a.csv")
+	h.clock.Advance(testQuiet)
+	if records := h.collect(); len(records) != 1 || !equalStrings(records[0].paths, []string{path}) {
+		t.Fatalf("records = %+v, want %s", records, filepath.Base(path))
+	}
+
+	// 目录可以监控后重载成功
+	h.watcher.WatchErrs = nil
+	if err := h.m.Reload(&newCfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.watcher.Watched(); len(got) != 3 {
+		t.Errorf("watched after successful reload = %v", got)
+	}
+}
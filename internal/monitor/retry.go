@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"errors"
+	"math/rand/v2"
+	"os/exec"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/model"
+)
+
+// retryPolicy 合并全局 settings 与监控项覆盖后的重试策略
+type retryPolicy struct {
+	// maxAttempts 总执行次数（首次执行 + 重试次数）
+	maxAttempts    int
+	delay          time.Duration
+	maxDelay       time.Duration
+	maxWindow      time.Duration
+	backoff        string
+	retryExitCodes map[int]bool
+	retryOnTimeout bool
+}
+
+// resolveRetryPolicy 计算监控项生效的重试策略
+func resolveRetryPolicy(settings model.Settings, mon config.Monitor) retryPolicy {
+	p := retryPolicy{
+		maxAttempts:    settings.RetryAttempts + 1,
+		delay:          time.Duration(settings.RetryDelaySeconds) * time.Second,
+		maxDelay:       time.Duration(settings.RetryMaxDelaySeconds) * time.Second,
+		maxWindow:      time.Duration(settings.RetryMaxWindowSeconds) * time.Second,
+		backoff:        settings.RetryBackoff,
+		retryOnTimeout: true,
+	}
+
+	if rc := mon.Retry; rc != nil {
+		if rc.Attempts != nil {
+			p.maxAttempts = *rc.Attempts + 1
+		}
+		if rc.DelaySeconds != nil {
+			p.delay = time.Duration(*rc.DelaySeconds) * time.Second
+		}
+		if rc.Backoff != "" {
+			p.backoff = rc.Backoff
+		}
+		if rc.MaxDelaySeconds > 0 {
+			p.maxDelay = time.Duration(rc.MaxDelaySeconds) * time.Second
+		}
+		if rc.MaxWindowSeconds > 0 {
+			p.maxWindow = time.Duration(rc.MaxWindowSeconds) * time.Second
+		}
+		if rc.RetryOnTimeout != nil {
+			p.retryOnTimeout = *rc.RetryOnTimeout
+		}
+		if len(rc.RetryOnExitCodes) > 0 {
+			p.retryExitCodes = make(map[int]bool, len(rc.RetryOnExitCodes))
+			for _, code := range rc.RetryOnExitCodes {
+				p.retryExitCodes[code] = true
+			}
+		}
+	}
+
+	if p.maxAttempts < 1 {
+		p.maxAttempts = 1
+	}
+	return p
+}
+
+// shouldRetry 判断失败是否允许重试，并返回判定原因
+func (p retryPolicy) shouldRetry(err error) (bool, string) {
+	if err == nil {
+		return false, "success"
+	}
+
+	if errors.Is(err, ErrFileNotFound) {
+		return false, "file not found"
+	}
+
+	if errors.Is(err, ErrCommandTimeout) {
+		return p.retryOnTimeout, "timeout"
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if p.retryExitCodes == nil {
+			return true, "non-zero exit code"
+		}
+		if p.retryExitCodes[exitErr.ExitCode()] {
+			return true, "retryable exit code"
+		}
+		return false, "exit code not retryable"
+	}
+
+	// 启动失败等其他错误：仅在未限定退出码时重试
+	return p.retryExitCodes == nil, "execution error"
+}
+
+// backoffDelay 返回第 attempt 次失败后的等待时间（attempt 从 1 开始）
+func (p retryPolicy) backoffDelay(attempt int) time.Duration {
+	d := p.delay
+	switch p.backoff {
+	case config.RetryBackoffLinear:
+		d *= time.Duration(attempt)
+	case config.RetryBackoffExponential, config.RetryBackoffJitter:
+		for i := 1; i < attempt && (p.maxDelay <= 0 || d < p.maxDelay); i++ {
+			d *= 2
+		}
+	}
+
+	if p.maxDelay > 0 && d > p.maxDelay {
+		d = p.maxDelay
+	}
+
+	if p.backoff == config.RetryBackoffJitter && d > 0 {
+		// full jitter: 在 [0, d] 内随机取值，避免多个失败任务同时重试
+		d = time.Duration(rand.Int64N(int64(d) + 1))
+	}
+	return d
+}
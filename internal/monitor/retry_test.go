@@ -0,0 +1,152 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/model"
+)
+
+func TestRetryAttemptsSetting(t *testing.T) {
+	load := func(t *testing.T, settings string) *config.Config {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		data := `{"monitors":[{"directory":"` + t.TempDir() + `","command":"false","file_patterns":["*"],"timeout":10,"enabled":true}],"settings":` + settings + `}`
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := config.LoadConfig(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	t.Run("default does not retry", func(t *testing.T) {
+		cfg := load(t, `{}`)
+		if got := resolveRetryPolicy(cfg.Settings, cfg.Monitors[0]).maxAttempts; got != 1 {
+			t.Errorf("maxAttempts = %d, want 1", got)
+		}
+	})
+
+	t.Run("opt in", func(t *testing.T) {
+		cfg := load(t, `{"retry_attempts":2}`)
+		if got := resolveRetryPolicy(cfg.Settings, cfg.Monitors[0]).maxAttempts; got != 3 {
+			t.Errorf("maxAttempts = %d, want 3", got)
+		}
+	})
+
+	t.Run("zero runs once", func(t *testing.T) {
+		cfg := load(t, `{"retry_attempts":0}`)
+		policy := resolveRetryPolicy(cfg.Settings, cfg.Monitors[0])
+		if policy.maxAttempts != 1 {
+			t.Fatalf("maxAttempts = %d, want 1", policy.maxAttempts)
+		}
+
+		h := newMonitorHarness(t, testNow, config.Monitor{Name: "m", Command: "false", FilePatterns: []string{"*"}})
+		var runs int32
+		h.m.runCommand = func(ctx context.Context, executor *CommandExecutor, monitor config.Monitor, event *model.FileEvent) (*ExecResult, error) {
+			atomic.AddInt32(&runs, 1)
+			return nil, errors.New("command failed")
+		}
+		event := model.FileEvent{Type: model.FileCreated, Path: filepath.Join(h.dir, "a.csv")}
+		completed, err := h.m.executeAttempts(cfg.Monitors[0], h.m.newExecutor(cfg.Monitors[0], event), &event, policy)
+		if !completed || err == nil {
+			t.Fatalf("completed = %v, err = %v; want a completed failure", completed, err)
+		}
+		if runs != 1 {
+			t.Errorf("command ran %d times, want 1", runs)
+		}
+	})
+}
+
+func TestBackoffDelay(t *testing.T) {
+	const s = time.Second
+	tests := []struct {
+		name     string
+		backoff  string
+		maxDelay time.Duration
+		want     []time.Duration
+	}{
+		{"fixed", config.RetryBackoffFixed, 0, []time.Duration{5 * s, 5 * s, 5 * s, 5 * s}},
+		{"linear", config.RetryBackoffLinear, 0, []time.Duration{5 * s, 10 * s, 15 * s, 20 * s}},
+		{"exponential", config.RetryBackoffExponential, 0, []time.Duration{5 * s, 10 * s, 20 * s, 40 * s}},
+		{"linear capped", config.RetryBackoffLinear, 12 * s, []time.Duration{5 * s, 10 * s, 12 * s, 12 * s}},
+		{"exponential capped", config.RetryBackoffExponential, 12 * s, []time.Duration{5 * s, 10 * s, 12 * s, 12 * s}},
+		{"fixed above cap", config.RetryBackoffFixed, 3 * s, []time.Duration{3 * s, 3 * s, 3 * s, 3 * s}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := retryPolicy{delay: 5 * s, maxDelay: tc.maxDelay, backoff: tc.backoff}
+			for i, want := range tc.want {
+				if got := p.backoffDelay(i + 1); got != want {
+					t.Errorf("attempt %d: delay = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("jitter stays within the exponential delay", func(t *testing.T) {
+		p := retryPolicy{delay: 5 * s, maxDelay: 12 * s, backoff: config.RetryBackoffJitter}
+		for attempt, limit := range []time.Duration{5 * s, 10 * s, 12 * s} {
+			for i := 0; i < 100; i++ {
+				if got := p.backoffDelay(attempt + 1); got < 0 || got > limit {
+					t.Fatalf("attempt %d: delay = %v, want within [0, %v]", attempt+1, got, limit)
+				}
+			}
+		}
+	})
+}
+
+// exitError 返回以 code 退出的命令的错误
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("/bin/sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("exit %d: got %v, want an exit error", code, err)
+	}
+	return err
+}
+
+func TestShouldRetry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires /bin/sh")
+	}
+
+	timeout := fmt.Errorf("%w: %w", ErrCommandTimeout, errors.New("signal: killed"))
+	tests := []struct {
+		name      string
+		policy    retryPolicy
+		err       error
+		wantRetry bool
+	}{
+		{"success", retryPolicy{}, nil, false},
+		{"file not found", retryPolicy{retryOnTimeout: true}, ErrFileNotFound, false},
+		{"any exit code", retryPolicy{}, exitError(t, 1), true},
+		{"listed exit code", retryPolicy{retryExitCodes: map[int]bool{75: true}}, exitError(t, 75), true},
+		{"unlisted exit code", retryPolicy{retryExitCodes: map[int]bool{75: true}}, exitError(t, 1), false},
+		{"timeout retried", retryPolicy{retryOnTimeout: true}, timeout, true},
+		{"timeout not retried", retryPolicy{retryOnTimeout: false}, timeout, false},
+		{"start failure", retryPolicy{}, errors.New("exec: not found"), true},
+		{"start failure with listed exit codes", retryPolicy{retryExitCodes: map[int]bool{75: true}}, errors.New("exec: not found"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, reason := tc.policy.shouldRetry(tc.err); got != tc.wantRetry {
+				t.Errorf("shouldRetry(%v) = %v (%s), want %v", tc.err, got, reason, tc.wantRetry)
+			}
+		})
+	}
+}
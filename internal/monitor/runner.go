@@ -3,14 +3,18 @@ package monitor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
+	"dir-monitor-go/internal/config"
 	"dir-monitor-go/internal/logger"
 	"dir-monitor-go/internal/model"
 )
@@ -18,12 +22,43 @@ import (
 const (
 	DefaultCommandTimeout   = 30
 	CommandOutputBufferSize = 4096
+	// 超时终止进程后等待输出管道关闭的最长时间
+	CommandWaitDelay = 5 * time.Second
 )
 
+var (
+	// ErrFileNotFound 触发文件在执行前已不存在
+	ErrFileNotFound = errors.New("file not found")
+	// ErrCommandTimeout 命令执行超过超时时间被终止
+	ErrCommandTimeout = errors.New("command timeout")
+)
+
+// ExecResult 一次命令执行的结果
+type ExecResult struct {
+	Start time.Time
+	End   time.Time
+	// ExitCode 为 -1 表示进程未正常退出（被信号终止或未启动）
+	ExitCode int
+	TimedOut bool
+	// Stdout/Stderr 为输出末尾片段（受 output.tail_bytes 限制）
+	Stdout string
+	Stderr string
+	// OutputFile 输出文件路径（启用 output.to_file 时）
+	OutputFile      string
+	OutputTruncated bool
+}
+
 type CommandExecutor struct {
 	logger     *logger.Logger
 	workingDir string
 	envVars    map[string]string
+	fileList   []string
+	stdin      []byte
+	logFields  []logger.Field
+	output     outputOptions
+
+	// rawSubstitution 为 true 时变量值不加引号（旧行为）
+	rawSubstitution bool
 }
 
 func NewCommandExecutor(logger *logger.Logger, workingDir string) *CommandExecutor {
@@ -31,6 +66,12 @@ func NewCommandExecutor(logger *logger.Logger, workingDir string) *CommandExecut
 		logger:     logger,
 		workingDir: workingDir,
 		envVars:    make(map[string]string),
+		output: outputOptions{
+			maxBytes:    config.DefaultOutputMaxBytes,
+			tailBytes:   config.DefaultOutputTailBytes,
+			stdoutLevel: config.DefaultOutputLogLevel,
+			stderrLevel: config.DefaultOutputLogLevel,
+		},
 	}
 }
 
@@ -38,31 +79,92 @@ func (ce *CommandExecutor) SetEnvVar(key, value string) {
 	ce.envVars[key] = value
 }
 
-func (ce *CommandExecutor) ExecuteCommand(command string, event *model.FileEvent, timeout int) error {
-	return ce.ExecuteCommandWithContext(context.Background(), command, event, timeout)
+// SetFileList 设置批处理文件列表，命令中的 ${FILE_LIST} 替换为空格分隔的路径，
+// 环境变量 FILE_LIST 为换行分隔的路径
+func (ce *CommandExecutor) SetFileList(paths []string) {
+	ce.fileList = append([]string(nil), paths...)
+	ce.envVars["FILE_LIST"] = strings.Join(paths, "\n")
+	ce.envVars["FILE_COUNT"] = strconv.Itoa(len(paths))
 }
 
-func (ce *CommandExecutor) ExecuteCommandWithContext(ctx context.Context, command string, event *model.FileEvent, timeout int) error {
-	ce.logger.Info("Execute command: %s - File: %s", command, event.Path)
+// SetSubstitutionMode 设置命令变量替换模式（quote/raw）
+func (ce *CommandExecutor) SetSubstitutionMode(mode string) {
+	ce.rawSubstitution = mode == config.SubstitutionRaw
+}
 
-	if _, err := os.Stat(event.Path); err != nil {
-		ce.logger.Error("File not found, skip execution: %s", event.Path)
-		return fmt.Errorf("file not found: %s", event.Path)
+// SetLogFields 设置附加到本执行器所有日志的结构化字段（如 monitor_id）
+func (ce *CommandExecutor) SetLogFields(fields ...logger.Field) {
+	ce.logFields = append(ce.logFields, fields...)
+}
+
+// log 返回附带执行器字段与事件字段的日志记录器
+func (ce *CommandExecutor) log(event *model.FileEvent) *logger.Entry {
+	entry := ce.logger.WithFields(ce.logFields...)
+	if event != nil {
+		entry = entry.WithFields(eventFields(*event)...)
 	}
+	return entry
+}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
-	defer cancel()
+// SetOutputOptions 设置命令输出处理选项
+func (ce *CommandExecutor) SetOutputOptions(opts outputOptions) {
+	ce.output = opts
+}
+
+// SetStdin 设置写入命令标准输入的数据
+func (ce *CommandExecutor) SetStdin(data []byte) {
+	ce.stdin = data
+}
+
+func (ce *CommandExecutor) ExecuteCommand(command string, event *model.FileEvent, timeout int) error {
+	_, err := ce.ExecuteCommandWithContext(context.Background(), command, event, timeout)
+	return err
+}
+
+// ExecuteCommandWithContext 经 shell 执行命令。命令未能启动时返回的结果为 nil。
+func (ce *CommandExecutor) ExecuteCommandWithContext(ctx context.Context, command string, event *model.FileEvent, timeout int) (*ExecResult, error) {
+	ce.log(event).WithFields(logger.String("command", command)).Info("Execute command")
 
 	if err := validateCommand(command); err != nil {
-		return fmt.Errorf("command validation failed: %w", err)
+		return nil, fmt.Errorf("command validation failed: %w", err)
 	}
 
-	cmd, err := ce.buildCommand(ctx, command, event)
+	return ce.execute(ctx, event, timeout, func(ctx context.Context) (*exec.Cmd, error) {
+		return ce.buildCommand(ctx, command, event)
+	})
+}
+
+// ExecuteArgsWithContext 以 exec 形式执行命令：不经过 shell，每个参数单独替换变量
+func (ce *CommandExecutor) ExecuteArgsWithContext(ctx context.Context, args []string, event *model.FileEvent, timeout int) (*ExecResult, error) {
+	ce.log(event).WithFields(logger.Any("args", args)).Info("Execute args")
+
+	if len(args) == 0 || strings.TrimSpace(args[0]) == "" {
+		return nil, fmt.Errorf("command validation failed: empty args")
+	}
+
+	return ce.execute(ctx, event, timeout, func(ctx context.Context) (*exec.Cmd, error) {
+		return ce.buildArgsCommand(ctx, args, event)
+	})
+}
+
+// execute 执行前检查触发文件（删除事件除外；批处理时至少一个文件仍存在），
+// 设置超时、工作目录、环境变量与标准输入后运行命令
+func (ce *CommandExecutor) execute(ctx context.Context, event *model.FileEvent, timeout int, build func(context.Context) (*exec.Cmd, error)) (*ExecResult, error) {
+	// 删除事件的触发文件本就不存在
+	if event.Type != model.FileDeleted && !anyExists(ce.paths(event)) {
+		ce.log(event).Error("File not found, skip execution")
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, strings.Join(ce.paths(event), ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	cmd, err := build(ctx)
 	if err != nil {
-		return fmt.Errorf("build command failed: %w", err)
+		return nil, fmt.Errorf("build command failed: %w", err)
 	}
 
-	ce.logger.Debug("Execute command: %v", cmd.Args)
+	ce.log(event).WithFields(logger.Any("argv", cmd.Args)).Debug("Execute command")
 
 	if ce.workingDir != "" {
 		cmd.Dir = ce.workingDir
@@ -74,7 +176,21 @@ func (ce *CommandExecutor) ExecuteCommandWithContext(ctx context.Context, comman
 	}
 	cmd.Env = env
 
-	return ce.runCommand(ctx, cmd)
+	if ce.stdin != nil {
+		cmd.Stdin = bytes.NewReader(ce.stdin)
+	}
+
+	return ce.runCommand(ctx, cmd, event)
+}
+
+// anyExists 判断 paths 中是否至少有一个文件存在
+func anyExists(paths []string) bool {
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func (ce *CommandExecutor) buildCommand(ctx context.Context, command string, event *model.FileEvent) (*exec.Cmd, error) {
@@ -93,13 +209,40 @@ func (ce *CommandExecutor) buildCommand(ctx context.Context, command string, eve
 	return cmd, nil
 }
 
-func (ce *CommandExecutor) runCommand(ctx context.Context, cmd *exec.Cmd) error {
-	var outBuf, errBuf bytes.Buffer
-	cmd.Stdout = &outBuf
-	cmd.Stderr = &errBuf
+func (ce *CommandExecutor) buildArgsCommand(ctx context.Context, args []string, event *model.FileEvent) (*exec.Cmd, error) {
+	argv := ce.replaceArgsVariables(args, event)
+	if len(argv) == 0 || strings.TrimSpace(argv[0]) == "" {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	setProcessGroup(cmd)
+	return cmd, nil
+}
+
+func (ce *CommandExecutor) runCommand(ctx context.Context, cmd *exec.Cmd, event *model.FileEvent) (*ExecResult, error) {
+	result := &ExecResult{Start: time.Now(), ExitCode: -1}
+
+	output := newCommandOutput(ce.output, ce.outputName(event), result.Start, ce.log(event))
+	cmd.Stdout = output.Stdout
+	cmd.Stderr = output.Stderr
+	cmd.WaitDelay = CommandWaitDelay
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("command start failed: %w", err)
+		output.Close()
+		result.End = time.Now()
+		return result, fmt.Errorf("command start failed: %w", err)
+	}
+
+	// finish 在进程结束、输出复制完成后填充结果
+	finish := func() {
+		output.Close()
+		output.Fill(result)
+		result.End = time.Now()
+		if cmd.ProcessState != nil {
+			result.ExitCode = cmd.ProcessState.ExitCode()
+		}
+		result.TimedOut = errors.Is(contextError(ctx), ErrCommandTimeout)
 	}
 
 	done := make(chan error, 1)
@@ -109,47 +252,236 @@ func (ce *CommandExecutor) runCommand(ctx context.Context, cmd *exec.Cmd) error
 
 	select {
 	case err := <-done:
-		output := strings.TrimSpace(outBuf.String() + "\n" + errBuf.String())
-		if output != "" {
-			ce.logger.Debug("Command output: %s", output)
-		}
+		finish()
 		if err != nil {
-			return fmt.Errorf("command execution failed: %w", err)
+			// CommandContext 可能先于本函数感知到超时并杀掉进程
+			if ctxErr := contextError(ctx); ctxErr != nil {
+				_ = killProcessTree(cmd.Process.Pid)
+				return result, ctxErr
+			}
+			return result, fmt.Errorf("command execution failed: %w", err)
 		}
-		return nil
+		return result, nil
 
 	case <-ctx.Done():
 		if cmd.Process != nil {
 			pid := cmd.Process.Pid
 			_ = killProcessTree(pid)
 		}
-		output := strings.TrimSpace(outBuf.String() + "\n" + errBuf.String())
-		if output != "" {
-			ce.logger.Debug("Command terminated output: %s", output)
-		}
-		return fmt.Errorf("command cancelled or timeout: %w", ctx.Err())
+		// 等待进程退出，确保输出不再被写入
+		<-done
+		finish()
+		return result, contextError(ctx)
+	}
+}
+
+// paths 返回本次执行涉及的文件：批处理为文件列表，否则为触发文件
+func (ce *CommandExecutor) paths(event *model.FileEvent) []string {
+	if len(ce.fileList) > 0 {
+		return ce.fileList
+	}
+	return []string{event.Path}
+}
+
+// outputName 输出文件名中的触发文件部分，批处理多个文件时为 batch
+func (ce *CommandExecutor) outputName(event *model.FileEvent) string {
+	if len(ce.fileList) > 1 {
+		return "batch"
+	}
+	return filepath.Base(event.Path)
+}
+
+// exitCode 返回命令退出码，非退出类错误返回 false
+func exitCode(err error) (int, bool) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
+
+// errorFields 执行错误的结构化日志字段（error，可用时附带 exit_code）
+func errorFields(err error) []logger.Field {
+	fields := []logger.Field{logger.Err(err)}
+	if code, ok := exitCode(err); ok {
+		fields = append(fields, logger.Int("exit_code", code))
+	}
+	return fields
+}
+
+// contextError 将上下文结束原因转换为执行错误，区分超时与取消
+func contextError(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", ErrCommandTimeout, err)
+	default:
+		return fmt.Errorf("command cancelled: %w", err)
 	}
 }
 
+// commandVarPattern 匹配命令中的变量引用：${VAR} 或 ${raw:VAR}
+var commandVarPattern = regexp.MustCompile(`\$\{(raw:)?([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// replaceCommandVariables 单次扫描替换命令中的已知变量（事件变量与监控项 env）。
+// quote 模式下变量值按引用所在的引号上下文转义：引号外整体加单引号，
+// 已写在双引号或单引号内的引用（如 "${FILE_PATH}"）只转义该引号内的特殊字符，
+// 因此旧配置中自行加引号的写法保持原有含义；${raw:VAR} 显式跳过转义。
+// 未知变量保持原样、未知的 ${raw:VAR} 改写为 ${VAR}，由 shell 在运行时从环境变量中展开。
 func (ce *CommandExecutor) replaceCommandVariables(command string, event *model.FileEvent) string {
-	command = strings.ReplaceAll(command, "${EVENT_TYPE}", string(event.Type))
-	command = strings.ReplaceAll(command, "${FILE_PATH}", event.Path)
-	command = strings.ReplaceAll(command, "${FILE_NAME}", filepath.Base(event.Path))
-	command = strings.ReplaceAll(command, "${FILE_DIR}", filepath.Dir(event.Path))
-	command = strings.ReplaceAll(command, "${EVENT_TIME}", event.Timestamp.Format(time.RFC3339))
+	matches := commandVarPattern.FindAllStringSubmatchIndex(command, -1)
+	if len(matches) == 0 {
+		return command
+	}
 
-	for k, v := range ce.envVars {
-		command = strings.ReplaceAll(command, "${"+k+"}", v)
+	var b strings.Builder
+	var ctx quoteContext
+	last := 0
+	for _, m := range matches {
+		ctx.scan(command[last:m[0]])
+		b.WriteString(command[last:m[0]])
+		last = m[1]
+
+		raw, name := m[2] >= 0, command[m[4]:m[5]]
+		values, ok := ce.lookupVariable(name, event)
+		switch {
+		case !ok && raw:
+			b.WriteString("${" + name + "}")
+		case !ok:
+			b.WriteString(command[m[0]:m[1]])
+		case raw || ce.rawSubstitution:
+			b.WriteString(strings.Join(values, " "))
+		default:
+			b.WriteString(ctx.quote(values))
+		}
+	}
+	b.WriteString(command[last:])
+	return b.String()
+}
+
+// quoteContext 跟踪扫描到的位置处于 shell 的哪种引号中
+type quoteContext struct {
+	// open 为 0（引号外）、'\'' 或 '"'
+	open byte
+	// escaped 上一个字符为未消费的反斜杠
+	escaped bool
+}
+
+// scan 按 POSIX shell 的引号规则推进状态
+func (q *quoteContext) scan(s string) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if q.escaped {
+			q.escaped = false
+			continue
+		}
+		switch q.open {
+		case 0:
+			switch c {
+			case '\\':
+				q.escaped = true
+			case '\'', '"':
+				q.open = c
+			}
+		case '\'':
+			if c == '\'' {
+				q.open = 0
+			}
+		case '"':
+			switch c {
+			case '\\':
+				q.escaped = true
+			case '"':
+				q.open = 0
+			}
+		}
+	}
+	// 变量引用本身不会被反斜杠转义
+	q.escaped = false
+}
+
+// quote 将变量值转义为当前引号上下文中的字面量：引号外每个值单独成为一个参数，
+// 引号内多个值以空格连接
+func (q *quoteContext) quote(values []string) string {
+	if !isWindows() {
+		switch q.open {
+		case '\'':
+			return strings.ReplaceAll(strings.Join(values, " "), "'", `'\''`)
+		case '"':
+			return doubleQuoteEscaper.Replace(strings.Join(values, " "))
+		}
+	}
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = shellQuote(v)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// doubleQuoteEscaper 转义在 shell 双引号内仍有特殊含义的字符
+var doubleQuoteEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`, "`", "\\`", `"`, `\"`)
+
+// replaceArgsVariables 逐个参数替换变量，值不加引号（不经过 shell，无需转义）。
+// 参数恰好为 ${FILE_LIST} 时展开为多个参数；未知变量保持原样。
+func (ce *CommandExecutor) replaceArgsVariables(args []string, event *model.FileEvent) []string {
+	argv := make([]string, 0, len(args))
+	for _, arg := range args {
+		if groups := commandVarPattern.FindStringSubmatch(arg); groups != nil && groups[0] == arg {
+			if values, ok := ce.lookupVariable(groups[2], event); ok {
+				argv = append(argv, values...)
+				continue
+			}
+		}
+
+		argv = append(argv, commandVarPattern.ReplaceAllStringFunc(arg, func(ref string) string {
+			name := commandVarPattern.FindStringSubmatch(ref)[2]
+			if values, ok := ce.lookupVariable(name, event); ok {
+				return strings.Join(values, " ")
+			}
+			return ref
+		}))
 	}
+	return argv
+}
 
-	for _, env := range os.Environ() {
-		parts := strings.SplitN(env, "=", 2)
-		if len(parts) == 2 {
-			command = strings.ReplaceAll(command, "${"+parts[0]+"}", parts[1])
+// lookupVariable 返回变量的取值；FILE_LIST 为多值，其余为单值
+func (ce *CommandExecutor) lookupVariable(name string, event *model.FileEvent) ([]string, bool) {
+	switch name {
+	case "EVENT_TYPE":
+		return []string{string(event.Type)}, true
+	case "FILE_PATH":
+		return []string{event.Path}, true
+	case "OLD_PATH":
+		return []string{event.OldPath}, true
+	case "FILE_NAME":
+		return []string{filepath.Base(event.Path)}, true
+	case "FILE_DIR":
+		return []string{filepath.Dir(event.Path)}, true
+	case "EVENT_TIME":
+		return []string{event.Timestamp.Format(time.RFC3339)}, true
+	case "FILE_LIST":
+		if ce.fileList != nil {
+			return ce.fileList, true
 		}
+	case "FILE_COUNT":
+		if ce.fileList != nil {
+			return []string{strconv.Itoa(len(ce.fileList))}, true
+		}
+	}
+
+	if v, ok := ce.envVars[name]; ok {
+		return []string{v}, true
 	}
+	return nil, false
+}
 
-	return command
+// shellQuote 将值转换为 shell 中的单个字面量参数
+func shellQuote(s string) string {
+	if isWindows() {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func isWindows() bool {
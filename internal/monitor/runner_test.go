@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+func newTestExecutor() *CommandExecutor {
+	return NewCommandExecutor(logger.NewLogger(logger.ERROR, io.Discard), "")
+}
+
+// runShell 以 /bin/sh -c 执行替换后的命令并返回标准输出
+func runShell(t *testing.T, commandLine string) string {
+	t.Helper()
+	out, err := exec.Command("/bin/sh", "-c", commandLine).Output()
+	if err != nil {
+		t.Fatalf("sh -c %q failed: %v", commandLine, err)
+	}
+	return string(out)
+}
+
+func TestReplaceCommandVariablesHostileFileNames(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell quoting only")
+	}
+
+	names := []struct {
+		name string
+		file string
+	}{
+		{"plain", "report.csv"},
+		{"spaces", "monthly report 2024.csv"},
+		{"semicolon", "a;rm -rf ~.csv"},
+		{"single quote", "it's.csv"},
+		{"double quote", `say "hi".csv`},
+		{"newline", "line1\nline2.csv"},
+		{"command substitution", "$(touch pwned).csv"},
+		{"backticks", "`id`.csv"},
+		{"variable", "${HOME}.csv"},
+		{"pipe and redirect", "a|b>c&d.csv"},
+		{"leading dash", "-rf.csv"},
+		{"glob", "*.csv"},
+	}
+
+	for _, tc := range names {
+		t.Run(tc.name, func(t *testing.T) {
+			ce := newTestExecutor()
+			event := &model.FileEvent{
+				Type:      model.FileCreated,
+				Path:      "/sftp/user2/data/" + tc.file,
+				Timestamp: time.Now(),
+			}
+
+			cmd := ce.replaceCommandVariables("printf '%s|%s' ${FILE_NAME} ${FILE_PATH}", event)
+			got := runShell(t, cmd)
+			want := tc.file + "|" + event.Path
+			if got != want {
+				t.Fatalf("substituted command %q printed %q, want %q", cmd, got, want)
+			}
+		})
+	}
+}
+
+func TestReplaceCommandVariablesFileList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell quoting only")
+	}
+
+	ce := newTestExecutor()
+	files := []string{"/d/a b.csv", "/d/$(x).csv", "/d/c'd.csv"}
+	ce.SetFileList(files)
+
+	cmd := ce.replaceCommandVariables("printf '[%s]' ${FILE_LIST}", &model.FileEvent{Path: files[0]})
+	got := runShell(t, cmd)
+	want := "[/d/a b.csv][/d/$(x).csv][/d/c'd.csv]"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplaceCommandVariablesRawAndUnknown(t *testing.T) {
+	ce := newTestExecutor()
+	ce.SetEnvVar("TARGET", "a b")
+	event := &model.FileEvent{Type: model.FileModified, Path: "/d/x.csv", OldPath: "/d/x.tmp"}
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+	}{
+		{"raw event variable", "cat ${raw:FILE_PATH}", "cat /d/x.csv"},
+		{"quoted env variable", "cp x ${TARGET}", "cp x 'a b'"},
+		{"raw env variable", "cp x ${raw:TARGET}", "cp x a b"},
+		{"unknown variable left to shell", "echo ${HOME}", "echo ${HOME}"},
+		{"unknown raw variable left to shell", "echo ${raw:HOME}", "echo ${HOME}"},
+		{"no recursive expansion", "echo ${EVENT_TYPE}", "echo 'modified'"},
+		{"old path", "mv ${OLD_PATH} ${FILE_PATH}", "mv '/d/x.tmp' '/d/x.csv'"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ce.replaceCommandVariables(tc.command, event); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReplaceCommandVariablesPreQuoted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("POSIX shell quoting only")
+	}
+
+	files := []string{
+		"/d/report.csv",
+		"/d/it's a $HOME `id` \\ \"x\".csv",
+	}
+	commands := []struct {
+		name    string
+		command string
+	}{
+		{"unquoted", "printf '%s|%s' ${FILE_NAME} ${FILE_PATH}"},
+		{"double quoted", `printf '%s|%s' "${FILE_NAME}" "${FILE_PATH}"`},
+		{"single quoted", `printf '%s|%s' '${FILE_NAME}' '${FILE_PATH}'`},
+		{"inside a quoted word", `printf '%s|%s' "${FILE_NAME}" "${FILE_DIR}/${FILE_NAME}"`},
+	}
+
+	for _, file := range files {
+		for _, tc := range commands {
+			t.Run(tc.name+"/"+filepath.Base(file), func(t *testing.T) {
+				ce := newTestExecutor()
+				cmd := ce.replaceCommandVariables(tc.command, &model.FileEvent{Type: model.FileCreated, Path: file})
+				got := runShell(t, cmd)
+				if want := filepath.Base(file) + "|" + file; got != want {
+					t.Fatalf("substituted command %q printed %q, want %q", cmd, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReplaceCommandVariablesUnknownRawExpandsFromEnvironment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires /bin/sh")
+	}
+
+	ce := newTestExecutor()
+	// 非批处理执行中 FILE_LIST 未设置，命令不能因 bad substitution 失败
+	cmd := ce.replaceCommandVariables("printf '[%s]' ${raw:FILE_LIST}", &model.FileEvent{Path: "/d/a.csv"})
+	if got := runShell(t, cmd); got != "[]" {
+		t.Fatalf("substituted command %q printed %q, want %q", cmd, got, "[]")
+	}
+}
+
+func TestValidateRawVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		monitor config.Monitor
+		wantErr bool
+	}{
+		{"known variable", config.Monitor{Command: "cat ${raw:FILE_PATH}"}, false},
+		{"env variable", config.Monitor{Command: "cat ${raw:TARGET}", Env: map[string]string{"TARGET": "x"}}, false},
+		{"unknown in command", config.Monitor{Command: "cat ${raw:FILEPATH}"}, true},
+		{"unknown in args", config.Monitor{Args: []string{"cat", "${raw:NOPE}"}}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mon := tc.monitor
+			mon.ID = "m"
+			mon.Directory = t.TempDir()
+			mon.FilePatterns = []string{"*"}
+			mon.Timeout = 1
+			cfg := &config.Config{Monitors: []config.Monitor{mon}}
+			if err := cfg.Validate(); (err != nil) != tc.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestReplaceCommandVariablesRawMode(t *testing.T) {
+	ce := newTestExecutor()
+	ce.SetSubstitutionMode(config.SubstitutionRaw)
+
+	got := ce.replaceCommandVariables("echo ${FILE_NAME}", &model.FileEvent{Path: "/d/a b.csv"})
+	if want := "echo a b.csv"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplaceCommandVariablesSingleSubstitutionPass(t *testing.T) {
+	ce := newTestExecutor()
+	ce.SetSubstitutionMode(config.SubstitutionRaw)
+
+	// 文件名中的变量引用不能被再次展开
+	got := ce.replaceCommandVariables("echo ${FILE_NAME}", &model.FileEvent{Path: "/d/${FILE_DIR}.csv"})
+	if want := "echo ${FILE_DIR}.csv"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplaceArgsVariables(t *testing.T) {
+	ce := newTestExecutor()
+	ce.SetFileList([]string{"/d/a b.csv", "/d/$(x).csv"})
+	event := &model.FileEvent{Type: model.FileCreated, Path: "/d/a b.csv"}
+
+	got := ce.replaceArgsVariables([]string{"/usr/bin/python", "--file=${FILE_PATH}", "${FILE_LIST}", "${HOME}"}, event)
+	want := []string{"/usr/bin/python", "--file=/d/a b.csv", "/d/a b.csv", "/d/$(x).csv", "${HOME}"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("arg %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestExecuteDeletedEvent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires /bin/sh")
+	}
+	ce := newTestExecutor()
+	missing := "/nonexistent/dir-monitor/gone.csv"
+
+	res, err := ce.ExecuteCommandWithContext(context.Background(), "printf %s ${FILE_PATH}",
+		&model.FileEvent{Type: model.FileDeleted, Path: missing}, 5)
+	if err != nil || res.Stdout != missing {
+		t.Fatalf("deleted event: stdout=%v err=%v", res, err)
+	}
+
+	_, err = ce.ExecuteCommandWithContext(context.Background(), "true",
+		&model.FileEvent{Type: model.FileCreated, Path: missing}, 5)
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("created event for missing file: err = %v, want ErrFileNotFound", err)
+	}
+}
+
+func TestExecuteBatchMissingFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires /bin/sh")
+	}
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "a.csv")
+	present := filepath.Join(dir, "b.csv")
+	if err := os.WriteFile(present, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 批处理的第一个文件已不存在时，其余文件仍可执行
+	ce := newTestExecutor()
+	ce.SetFileList([]string{missing, present})
+	if _, err := ce.ExecuteCommandWithContext(context.Background(), "true",
+		&model.FileEvent{Type: model.FileCreated, Path: missing}, 5); err != nil {
+		t.Errorf("batch with one present file: err = %v", err)
+	}
+
+	ce = newTestExecutor()
+	ce.SetFileList([]string{missing, filepath.Join(dir, "c.csv")})
+	_, err := ce.ExecuteCommandWithContext(context.Background(), "true",
+		&model.FileEvent{Type: model.FileCreated, Path: missing}, 5)
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("batch with no present file: err = %v, want ErrFileNotFound", err)
+	}
+}
@@ -0,0 +1,218 @@
+package monitor
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/ledger"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+const (
+	// LedgerSubdir 已处理文件记录在 data_dir 下的子目录
+	LedgerSubdir = "ledger"
+)
+
+// 启动扫描跳过文件的原因
+const (
+	scanSkipDisabled  = "scan_on_start disabled"
+	scanSkipTooOld    = "older than max_age_seconds"
+	scanSkipProcessed = "already processed"
+)
+
+// LedgerDir 返回配置对应的已处理文件记录目录
+func LedgerDir(cfg *config.Config) string {
+	dataDir := cfg.Settings.DataDir
+	if dataDir == "" {
+		dataDir = config.DefaultDataDir
+	}
+	return filepath.Join(dataDir, LedgerSubdir)
+}
+
+// openLedger 打开已处理文件记录；失败时仅告警，启动扫描将不做已处理检查
+func openLedger(cfg *config.Config, log *logger.Logger) *ledger.Store {
+	store, err := ledger.Open(LedgerDir(cfg))
+	if err != nil {
+		log.Warn("[Monitor] 无法打开已处理文件记录，启动扫描将不跳过已处理文件: %v", err)
+		return nil
+	}
+	return store
+}
+
+// recordProcessed 记录命令成功处理的文件（处置前的大小与修改时间）
+func (m *Monitor) recordProcessed(monitor config.Monitor, paths []string) {
+	if m.ledger == nil {
+		return
+	}
+	now := m.clock.Now()
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		entry := ledger.Entry{
+			MonitorID:   monitorKey(monitor),
+			Path:        path,
+			Size:        info.Size(),
+			ModTime:     info.ModTime(),
+			ProcessedAt: now,
+		}
+		if err := m.ledger.Add(entry); err != nil {
+			m.logger.WithFields(logger.String("path", path), logger.Err(err)).Warn("[Monitor] 写入已处理文件记录失败")
+		}
+	}
+}
+
+// scanAccepts 判断启动扫描发现的文件是否应交给监控项处理，不接受时返回原因
+func (m *Monitor) scanAccepts(monitor config.Monitor, path string, size int64, modTime, now time.Time) (bool, string) {
+	sc := monitor.ScanOnStart
+	if sc == nil {
+		return false, scanSkipDisabled
+	}
+	if sc.MaxAgeSeconds > 0 && now.Sub(modTime) > time.Duration(sc.MaxAgeSeconds)*time.Second {
+		return false, scanSkipTooOld
+	}
+	if sc.SkipProcessed && m.ledger != nil && m.ledger.Contains(monitorKey(monitor), path, size, modTime) {
+		return false, scanSkipProcessed
+	}
+	return true, ""
+}
+
+// scanMonitors 按目录分组返回启用了 scan_on_start 的监控项
+func (m *Monitor) scanMonitors(cfg *config.Config) map[string][]config.Monitor {
+	byDir := make(map[string][]config.Monitor)
+	for _, monitor := range cfg.Monitors {
+		if !monitor.Enabled || monitor.ScanOnStart == nil {
+			continue
+		}
+		if m.specificDir != "" && monitor.Directory != m.specificDir {
+			continue
+		}
+		byDir[monitor.Directory] = append(byDir[monitor.Directory], monitor)
+	}
+	return byDir
+}
+
+// scanOnStart 在开始监控后扫描目录中已存在的文件，
+// 将匹配的文件作为 created 事件送入事件通道（通道满时等待，不丢弃）
+func (m *Monitor) scanOnStart() {
+	defer m.wg.Done()
+
+	byDir := m.scanMonitors(m.currentConfig())
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	for _, dir := range dirs {
+		if !m.scanDirectory(dir, byDir[dir]) {
+			return
+		}
+	}
+}
+
+// scanFiles 返回 dir 及其 depth 层以内子目录中的普通文件，按路径排序；skip 返回 true 的子目录不进入
+func scanFiles(dir string, depth int, skip func(string) bool) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			// 扫描期间被删除或无法读取的条目
+			return nil
+		}
+		if entry.IsDir() {
+			if !withinDepth(dir, p, depth) || (p != dir && skip != nil && skip(p)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.Type().IsRegular() {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// scanDirectory 扫描单个目录（递归的监控项包含其子目录），监控停止时返回 false
+func (m *Monitor) scanDirectory(dir string, monitors []config.Monitor) bool {
+	log := m.logger.WithFields(logger.String("dir", dir))
+	depth := 0
+	for _, monitor := range monitors {
+		if d := monitor.WatchDepth(); d == config.UnlimitedDepth || (depth != config.UnlimitedDepth && d > depth) {
+			depth = d
+		}
+	}
+	// 全部监控项的处置目标目录（如 quarantine）中的文件已处理过，不进入
+	skip := func(sub string) bool {
+		for _, monitor := range monitors {
+			if !monitor.InDispositionDir(sub) {
+				return false
+			}
+		}
+		return true
+	}
+	files, err := scanFiles(dir, depth, skip)
+	if err != nil {
+		log.WithFields(logger.Err(err)).Error("[Monitor] 启动扫描读取目录失败")
+		return true
+	}
+
+	now := m.clock.Now()
+	queued := 0
+	skipped := make(map[string]int)
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		accepted := false
+		for _, monitor := range monitors {
+			if !monitor.Covers(path) || !m.selectsFile(monitor, path) || m.isIgnored(monitor, path) {
+				continue
+			}
+			ok, reason := m.scanAccepts(monitor, path, info.Size(), info.ModTime(), now)
+			if ok {
+				accepted = true
+				break
+			}
+			skipped[reason]++
+		}
+		if !accepted {
+			continue
+		}
+
+		event := model.FileEvent{
+			Type:      model.FileCreated,
+			Path:      path,
+			Directory: filepath.Dir(path),
+			Timestamp: now,
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+			Synthetic: true,
+		}
+		select {
+		case m.eventChannel <- event:
+			queued++
+			log.WithFields(eventFields(event)...).Debug("[Monitor] 启动扫描发现待处理文件")
+		case <-m.stopChan:
+			return false
+		}
+	}
+
+	log.WithFields(
+		logger.Int("queued", queued),
+		logger.Int("skipped_too_old", skipped[scanSkipTooOld]),
+		logger.Int("skipped_processed", skipped[scanSkipProcessed]),
+	).Info("[Monitor] 启动扫描完成")
+	return true
+}
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adhocore/gronx"
+
+	"dir-monitor-go/internal/calendar"
+	"dir-monitor-go/internal/config"
+)
+
+const (
+	// 计算下次调度窗口时，因日历不允许而跳过的最大次数
+	maxScheduleSearchSteps = 2 * 366
+
+	// 仅配置日历时使用的调度表达式（日历允许的日期全天执行）
+	everyMinuteSchedule = "* * * * *"
+)
+
+// scheduleData 调度使用的时区与日历
+type scheduleData struct {
+	calendars map[string]*calendar.Calendar
+	locations map[string]*time.Location
+}
+
+// loadScheduleData 加载配置中的日历与监控项时区
+func loadScheduleData(cfg *config.Config) (*scheduleData, error) {
+	calendars, err := cfg.LoadCalendars()
+	if err != nil {
+		return nil, err
+	}
+	sd := &scheduleData{calendars: calendars, locations: make(map[string]*time.Location)}
+	for _, monitor := range cfg.Monitors {
+		if monitor.Timezone == "" || sd.locations[monitor.Timezone] != nil {
+			continue
+		}
+		loc, err := monitor.Location()
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone for monitor %s: %v", monitor.Directory, err)
+		}
+		sd.locations[monitor.Timezone] = loc
+	}
+	return sd, nil
+}
+
+func (sd *scheduleData) location(monitor config.Monitor) *time.Location {
+	if loc := sd.locations[monitor.Timezone]; loc != nil {
+		return loc
+	}
+	return time.Local
+}
+
+func (sd *scheduleData) calendar(monitor config.Monitor) *calendar.Calendar {
+	if monitor.Calendar == "" {
+		return nil
+	}
+	return sd.calendars[monitor.Calendar]
+}
+
+// currentSchedules 返回当前配置对应的时区与日历
+func (m *Monitor) currentSchedules() *scheduleData {
+	m.cfgMu.RLock()
+	defer m.cfgMu.RUnlock()
+	return m.schedules
+}
+
+// isScheduleActive 按监控项的时区判断当前时间是否在调度窗口内且日历允许
+func (m *Monitor) isScheduleActive(monitor config.Monitor) bool {
+	if !monitor.HasSchedule() {
+		m.logger.Debug("[Monitor] 调度检查: 无调度表达式，总是激活")
+		return true
+	}
+
+	sd := m.currentSchedules()
+	now := m.clock.Now().In(sd.location(monitor)).Truncate(time.Minute)
+
+	if cal := sd.calendar(monitor); !cal.Allows(now) {
+		m.logger.Info("[Monitor] 日历不允许当天执行，跳过: 日历=%s, 当前时间=%v", monitor.Calendar, now)
+		return false
+	}
+	if monitor.Schedule == "" {
+		return true
+	}
+
+	gx := gronx.New()
+	due, err := gx.IsDue(monitor.Schedule, now)
+	if err != nil {
+		m.logger.Error("[Monitor] 调度表达式解析错误: %v, 表达式: %s", err, monitor.Schedule)
+		return false
+	}
+
+	m.logger.Debug("[Monitor] 调度检查详情: 表达式=%s, 当前时间=%v, 星期=%d, 小时=%d, 分钟=%d, 是否匹配=%v",
+		monitor.Schedule, now, now.Weekday(), now.Hour(), now.Minute(), due)
+
+	if !due {
+		m.logger.Info("[Monitor] 调度不匹配，跳过执行: 表达式=%s, 当前时间=%v", monitor.Schedule, now)
+	}
+
+	return due
+}
+
+// nextScheduleWindow 返回 now 之后调度表达式下一次匹配且日历允许的时间（监控项时区）
+func (m *Monitor) nextScheduleWindow(monitor config.Monitor, now time.Time) (time.Time, error) {
+	sd := m.currentSchedules()
+	loc := sd.location(monitor)
+	cal := sd.calendar(monitor)
+
+	expr := monitor.Schedule
+	if expr == "" {
+		expr = everyMinuteSchedule
+	}
+
+	ref := now.In(loc)
+	incl := false
+	for i := 0; i < maxScheduleSearchSteps; i++ {
+		next, err := gronx.NextTickAfter(expr, ref, incl)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if cal.Allows(next) {
+			return next, nil
+		}
+		// 日历不允许当天，从次日零点继续查找
+		y, mo, d := next.Date()
+		ref = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		incl = true
+	}
+	return time.Time{}, fmt.Errorf("no schedule window allowed by calendar %s found", monitor.Calendar)
+}
@@ -0,0 +1,163 @@
+package monitor
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"dir-monitor-go/internal/config"
+	"dir-monitor-go/internal/logger"
+	"dir-monitor-go/internal/model"
+)
+
+// selectsFile 判断文件的事件是否进入监控项的目录缓冲区：trigger 模式只缓冲触发文件，
+// 数据文件在触发文件到达时从目录中收集
+func (m *Monitor) selectsFile(monitor config.Monitor, filePath string) bool {
+	if monitor.Trigger != nil {
+		return m.isTriggerFile(monitor, filePath)
+	}
+	return m.matchesFilePattern(monitor, filePath)
+}
+
+// triggerBatchFiles 收集触发文件所在目录中匹配 file_patterns、未被忽略且满足属性过滤条件的数据文件；
+// 已由之前的触发成功处理且未再变化的文件（记录在已处理文件记录中）不再加入批次
+func (m *Monitor) triggerBatchFiles(monitor config.Monitor, trigger model.FileEvent) ([]model.FileEvent, error) {
+	dir := filepath.Dir(trigger.Path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.clock.Now()
+	files := make([]model.FileEvent, 0, len(entries))
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if !entry.Type().IsRegular() || m.isTriggerFile(monitor, path) || !m.matchesFilePattern(monitor, path) {
+			continue
+		}
+		if m.isIgnored(monitor, path) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if m.ledger != nil && m.ledger.Contains(monitorKey(monitor), path, info.Size(), info.ModTime()) {
+			m.logger.WithFields(logger.String("monitor_id", monitorKey(monitor)), logger.String("path", path)).
+				Debug("[Monitor] 数据文件已处理过，不加入批次")
+			continue
+		}
+		if m.rejectedByFilters(monitor, path, now) {
+			continue
+		}
+		files = append(files, model.FileEvent{
+			Type:      model.FileCreated,
+			Path:      path,
+			Directory: dir,
+			Timestamp: info.ModTime(),
+			Size:      info.Size(),
+			ModTime:   info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// executeTriggers 每个到达的触发文件执行一次命令，批次为其所在目录中的数据文件
+func (m *Monitor) executeTriggers(monitor config.Monitor, triggers []model.FileEvent, tracker *ackTracker) {
+	for _, trigger := range triggers {
+		m.executeTrigger(monitor, trigger, tracker)
+	}
+}
+
+// executeTrigger 以清单形式将数据文件交给命令，${TRIGGER_FILE} 为触发文件路径；
+// batch.mode 为 stdin 时同时写入标准输入
+func (m *Monitor) executeTrigger(monitor config.Monitor, trigger model.FileEvent, tracker *ackTracker) {
+	log := m.execLogger(monitor, trigger)
+	if _, err := os.Stat(trigger.Path); err != nil {
+		log.Info("[Monitor] 触发文件已不存在，跳过")
+		return
+	}
+
+	files, err := m.triggerBatchFiles(monitor, trigger)
+	if err != nil {
+		log.WithFields(logger.Err(err)).Error("[Monitor] 读取触发文件所在目录失败")
+		return
+	}
+	if len(files) == 0 {
+		log.Warn("[Monitor] 触发文件已到达，但目录中没有匹配的数据文件，跳过")
+		return
+	}
+	log = log.WithFields(logger.Int("file_count", len(files)))
+	log.Info("[Monitor] 触发文件已到达，批处理执行")
+
+	paths := eventPaths(files)
+	if m.isDuplicate(monitor, trigger.Path+"|"+strings.Join(paths, "|")) {
+		log.Info("[Monitor] 检测到重复批处理，跳过")
+		return
+	}
+
+	format := config.ManifestFormatLines
+	if monitor.Batch != nil && monitor.Batch.ManifestFormat != "" {
+		format = monitor.Batch.ManifestFormat
+	}
+	manifest, err := writeManifest(files, format)
+	if err != nil {
+		log.WithFields(logger.Err(err)).Error("[Monitor] 创建批处理清单失败")
+		return
+	}
+
+	executor := m.newExecutor(monitor, trigger)
+	executor.SetFileList(paths)
+	executor.SetEnvVar("TRIGGER_FILE", trigger.Path)
+	executor.SetEnvVar("MANIFEST", manifest)
+	if monitor.Batch != nil && monitor.Batch.Mode == config.BatchModeStdin {
+		data, err := json.Marshal(batchPayload{
+			MonitorID: monitor.ID,
+			Directory: monitor.Directory,
+			Files:     newBatchFiles(files),
+		})
+		if err != nil {
+			os.Remove(manifest)
+			log.WithFields(logger.Err(err)).Error("[Monitor] 编码批处理输入失败")
+			return
+		}
+		executor.SetStdin(data)
+	}
+
+	policy := resolveRetryPolicy(m.currentConfig().Settings, monitor)
+
+	m.wg.Add(1)
+	tracker.add()
+	go func() {
+		defer m.wg.Done()
+		defer os.Remove(manifest)
+
+		completed, err := m.executeAttempts(monitor, executor, &trigger, policy)
+		if !completed {
+			tracker.done(false)
+			return
+		}
+		if err == nil {
+			// 触发文件一并记录，启动扫描不会再次触发
+			m.recordProcessed(monitor, append(paths, trigger.Path))
+		}
+		m.applyDisposition(monitor, paths, err)
+		if err == nil && monitor.Trigger.Remove {
+			m.removeTrigger(monitor, trigger.Path)
+		}
+		tracker.done(true)
+	}()
+}
+
+// removeTrigger 命令执行成功后删除触发文件
+func (m *Monitor) removeTrigger(monitor config.Monitor, path string) {
+	log := m.logger.WithFields(logger.String("monitor_id", monitorKey(monitor)), logger.String("path", path))
+	// 不忽略删除产生的事件：上游可能很快放置同名的下一个触发文件，其事件不能被丢弃；
+	// 触发文件的删除事件在执行时因文件不存在而跳过
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.WithFields(logger.Err(err)).Error("[Monitor] 删除触发文件失败")
+		return
+	}
+	log.Info("[Monitor] 已删除触发文件")
+}
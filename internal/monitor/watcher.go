@@ -1,13 +1,18 @@
 package monitor
 
 import (
+	"path/filepath"
+	"strings"
+
+	"dir-monitor-go/internal/config"
 	"dir-monitor-go/internal/model"
 )
 
 // Watcher 监视器接口
 type Watcher interface {
-	// Watch 开始监控指定目录
-	Watch(dir string) error
+	// Watch 开始监控指定目录及其 depth 层以内的子目录：0 只监控顶层，
+	// config.UnlimitedDepth 不限制；之后新建的子目录按同样的层数自动加入监控
+	Watch(dir string, depth int) error
 	// Unwatch 停止监控指定目录
 	Unwatch(dir string) error
 	// Events 返回事件通道
@@ -15,3 +20,33 @@ type Watcher interface {
 	// Close 关闭监视器
 	Close() error
 }
+
+// WatchInspector 可选接口：监视器支持状态查询与重新注册时，健康检查与指标使用它
+type WatchInspector interface {
+	// IsWatching 目录是否仍处于监控中
+	IsWatching(dir string) bool
+	// Rewatch 重新建立目录监控
+	Rewatch(dir string) error
+	// WatchCount 已注册的监控数量
+	WatchCount() int
+	// ErrorCount 累计错误数
+	ErrorCount() uint64
+}
+
+// dirDepth 返回目录 dir 相对于 root 的层数：root 为 0，直接子目录为 1；dir 不在 root 之下时返回 -1
+func dirDepth(root, dir string) int {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return -1
+	}
+	if rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// withinDepth 判断 root 下的目录 dir 是否在 depth 层以内
+func withinDepth(root, dir string, depth int) bool {
+	d := dirDepth(root, dir)
+	return d >= 0 && (depth == config.UnlimitedDepth || d <= depth)
+}
@@ -0,0 +1,151 @@
+// Package pattern 监控项的文件模式。
+//
+//   - 不含 / 的 glob 匹配文件名，如 *.csv
+//   - 含 / 的 glob 匹配相对于监控目录的路径，** 匹配零或多层目录，如 reports/2024-*/**/*.xlsx
+//   - re: 开头的模式为正则表达式，在以 / 分隔的相对路径中查找匹配（需要完整匹配时自行使用 ^ 与 $）
+//   - ! 开头的模式为排除，\! 表示字面量 !
+//
+// 文件需匹配至少一个包含模式，且不匹配任何排除模式。
+package pattern
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexpPrefix 正则表达式模式的前缀
+const RegexpPrefix = "re:"
+
+// Set 编译后的一组文件模式
+type Set struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// Compile 编译文件模式；caseInsensitive 为 true 时匹配不区分大小写
+func Compile(patterns []string, caseInsensitive bool) (*Set, error) {
+	s := &Set{}
+	for _, p := range patterns {
+		re, negate, err := compileOne(p, caseInsensitive)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %v", p, err)
+		}
+		if negate {
+			s.exclude = append(s.exclude, re)
+		} else {
+			s.include = append(s.include, re)
+		}
+	}
+	return s, nil
+}
+
+// Match 判断以 / 分隔的相对路径是否匹配
+func (s *Set) Match(rel string) bool {
+	if s == nil {
+		return false
+	}
+	for _, re := range s.exclude {
+		if re.MatchString(rel) {
+			return false
+		}
+	}
+	for _, re := range s.include {
+		if re.MatchString(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileOne(p string, caseInsensitive bool) (re *regexp.Regexp, negate bool, err error) {
+	switch {
+	case strings.HasPrefix(p, "!"):
+		negate = true
+		p = p[1:]
+	case strings.HasPrefix(p, `\!`):
+		p = p[1:]
+	}
+	if p == "" {
+		return nil, false, fmt.Errorf("empty pattern")
+	}
+
+	var expr string
+	if strings.HasPrefix(p, RegexpPrefix) {
+		expr = strings.TrimPrefix(p, RegexpPrefix)
+		if expr == "" {
+			return nil, false, fmt.Errorf("empty regular expression")
+		}
+	} else {
+		if !strings.Contains(p, "/") {
+			// 文件名模式匹配任意层级的文件名
+			p = "**/" + p
+		}
+		if expr, err = GlobToRegexp(p); err != nil {
+			return nil, false, err
+		}
+	}
+	if caseInsensitive {
+		expr = "(?i)" + expr
+	}
+	re, err = regexp.Compile(expr)
+	if err != nil {
+		return nil, false, err
+	}
+	return re, negate, nil
+}
+
+// GlobToRegexp 将 glob 转换为匹配完整相对路径的正则表达式：
+// * 与 ? 不跨越 /，[...] 为字符类（[!...] 取反），** 匹配零或多层目录，\ 转义下一个字符
+func GlobToRegexp(p string) (string, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				atStart := i == 0 || p[i-1] == '/'
+				switch {
+				case atStart && i+2 < len(p) && p[i+2] == '/':
+					// **/ 匹配零或多层目录
+					b.WriteString("(?:.*/)?")
+					i += 2
+				case atStart && i+2 == len(p):
+					// 结尾的 ** 匹配其下的全部内容
+					b.WriteString(".*")
+					i++
+				default:
+					b.WriteString("[^/]*")
+					i++
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated character class")
+			}
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(p) {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(p[i])))
+			} else {
+				b.WriteString(regexp.QuoteMeta(`\`))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String(), nil
+}
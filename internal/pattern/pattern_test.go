@@ -0,0 +1,53 @@
+package pattern
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		patterns        []string
+		caseInsensitive bool
+		path            string
+		want            bool
+	}{
+		{"base name", []string{"*.csv"}, false, "a.csv", true},
+		{"base name in subdirectory", []string{"*.csv"}, false, "in/a.csv", true},
+		{"base name mismatch", []string{"*.csv"}, false, "a.txt", false},
+		{"case sensitive", []string{"*.csv"}, false, "A.CSV", false},
+		{"case insensitive", []string{"*.csv"}, true, "A.CSV", true},
+		{"relative glob", []string{"reports/2024-*/**/*.xlsx"}, false, "reports/2024-01/a/b/x.xlsx", true},
+		{"relative glob zero dirs", []string{"reports/2024-*/**/*.xlsx"}, false, "reports/2024-01/x.xlsx", true},
+		{"relative glob other year", []string{"reports/2024-*/**/*.xlsx"}, false, "reports/2023-01/x.xlsx", false},
+		{"relative glob is anchored", []string{"in/*.csv"}, false, "x/in/a.csv", false},
+		{"star does not cross slash", []string{"in/*.csv"}, false, "in/a/b.csv", false},
+		{"regexp", []string{`re:^in/\d{8}\.csv$`}, false, "in/20260304.csv", true},
+		{"regexp mismatch", []string{`re:^in/\d{8}\.csv$`}, false, "in/today.csv", false},
+		{"regexp case insensitive", []string{`re:\.csv$`}, true, "A.CSV", true},
+		{"exclusion", []string{"*.csv", "!*_partial.csv"}, false, "a_partial.csv", false},
+		{"exclusion order independent", []string{"!*_partial.csv", "*.csv"}, false, "a_partial.csv", false},
+		{"exclusion keeps others", []string{"*.csv", "!*_partial.csv"}, false, "a.csv", true},
+		{"only exclusions", []string{"!*.tmp"}, false, "a.csv", false},
+		{"no patterns", nil, false, "a.csv", false},
+		{"escaped bang", []string{`\!*.csv`}, false, "!a.csv", true},
+		{"char class", []string{"file[0-9].txt"}, false, "file7.txt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Compile(tt.patterns, tt.caseInsensitive)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, p := range []string{"re:([", "file[0-9", "!", "re:"} {
+		if _, err := Compile([]string{p}, false); err == nil {
+			t.Errorf("Compile(%q) should fail", p)
+		}
+	}
+}